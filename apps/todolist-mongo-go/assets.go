@@ -0,0 +1,38 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// embeddedAssets holds the web UI so the binary serves it from any working
+// directory
+//
+//go:embed index.html favicon.ico resources
+var embeddedAssets embed.FS
+
+// assets is the filesystem the web UI is served from. Set by loadAssets.
+var assets fs.FS = embeddedAssets
+
+// loadAssets selects the embedded web UI, or the files in the working
+// directory when STATIC_FROM_DISK=true so edits show up without a rebuild
+func loadAssets() {
+	if getEnvBool("STATIC_FROM_DISK", false) {
+		log.Info("Serving web UI from the working directory")
+		assets = os.DirFS(".")
+		return
+	}
+	assets = embeddedAssets
+}
+
+// resourcesFS returns the resources/ subtree served under /resources/
+func resourcesFS() fs.FS {
+	sub, err := fs.Sub(assets, "resources")
+	if err != nil {
+		log.Fatalf("Failed to open resources: %v", err)
+	}
+	return sub
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Audit operations
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+var validAuditOperations = map[string]bool{auditCreate: true, auditUpdate: true, auditDelete: true}
+
+// AuditEntry records one change to one item. Owner is the X-User-ID of the
+// caller that made it and Route the endpoint it went through.
+type AuditEntry struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Time      time.Time          `bson:"time" json:"time"`
+	Operation string             `bson:"operation" json:"operation"`
+	ItemID    primitive.ObjectID `bson:"itemId" json:"itemId"`
+	Owner     string             `bson:"owner,omitempty" json:"owner,omitempty"`
+	Route     string             `bson:"route,omitempty" json:"route,omitempty"`
+}
+
+// auditCollection is the append-only audit log kept next to collection,
+// named like its backups so every tenant has its own
+func auditCollection(collection *mongo.Collection) *mongo.Collection {
+	return collection.Database().Collection(collection.Name() + "_audit")
+}
+
+// auditIndexes serve GET /todo/audit: the history of one item newest first,
+// and the whole log by time
+func auditIndexes(background bool) []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "itemId", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("itemId_time").SetBackground(background)},
+		{Keys: bson.D{{Key: "time", Value: -1}}, Options: options.Index().SetName("time").SetBackground(background)},
+	}
+}
+
+// recordAudit appends an entry for each of ids. It runs after the change
+// has been written, so a failure is logged rather than failing the request.
+func recordAudit(r *http.Request, collection *mongo.Collection, operation string, ids ...primitive.ObjectID) {
+	if len(ids) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	owner, route := ownerFromRequest(r), routeOf(r)
+	entries := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, AuditEntry{Time: now, Operation: operation, ItemID: id, Owner: owner, Route: route})
+	}
+	ctx, cancel := opContext()
+	defer cancel()
+	if _, err := auditCollection(collection).InsertMany(ctx, entries); err != nil {
+		log.WithFields(log.Fields{"operation": operation, "count": len(ids)}).Errorf("Failed to record audit entries: %v", err)
+	}
+}
+
+// touchedIDs returns the items matching filter that were written with
+// updatedAt at, for bulk changes that only report counts
+func touchedIDs(collection *mongo.Collection, filter bson.M, at time.Time) ([]primitive.ObjectID, error) {
+	query := bson.M{"updatedAt": at}
+	for key, value := range filter {
+		query[key] = value
+	}
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Find(ctx, query, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var docs []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	touched := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		touched[i] = doc.Id
+	}
+	return touched, nil
+}
+
+// recordBulkAudit records operation for the items matching filter that a
+// bulk change wrote at now
+func recordBulkAudit(r *http.Request, collection *mongo.Collection, operation string, filter bson.M, now time.Time) {
+	touched, err := touchedIDs(collection, filter, now)
+	if err != nil {
+		log.Errorf("Failed to find the items to audit: %v", err)
+		return
+	}
+	recordAudit(r, collection, operation, touched...)
+}
+
+// buildAuditFilter reads GET /todo/audit's item_id, operation and the
+// RFC3339 since/until bounds on the entry time
+func buildAuditFilter(r *http.Request) (bson.M, error) {
+	query := r.URL.Query()
+	filter := bson.M{}
+	if value := query.Get("item_id"); value != "" {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item_id %q", value)
+		}
+		filter["itemId"] = id
+	}
+	if value := query.Get("operation"); value != "" {
+		if !validAuditOperations[value] {
+			return nil, fmt.Errorf("invalid operation %q: must be one of create, update, delete", value)
+		}
+		filter["operation"] = value
+	}
+	bounds := bson.M{}
+	for param, op := range map[string]string{"since": "$gte", "until": "$lte"} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q: must be an RFC3339 timestamp", param, value)
+			}
+			bounds[op] = t
+		}
+	}
+	if since, ok := bounds["$gte"].(time.Time); ok {
+		if until, ok := bounds["$lte"].(time.Time); ok && until.Before(since) {
+			return nil, fmt.Errorf("until must not be earlier than since")
+		}
+	}
+	if len(bounds) > 0 {
+		filter["time"] = bounds
+	}
+	return filter, nil
+}
+
+// HeadAuditLog counts the audit entries GET /todo/audit pages through with
+// the same filters
+func HeadAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := buildAuditFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	log.Info("Count audit entries")
+	writeTotalCount(w, auditCollection(todoCollection(r)), filter)
+}
+
+// GetAuditLog lists audit entries newest first, filtered by item_id,
+// operation (create, update or delete) and the since/until time range, and
+// paged with limit and offset. X-Total-Count carries the number of matches.
+// Admin: guarded by requireAPIKey, since entries carry every caller's
+// X-User-ID.
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	filter, err := buildAuditFilter(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	log.WithFields(log.Fields{"filter": filter, "limit": limit, "offset": offset}).Info("Get audit log")
+
+	audit := auditCollection(collection)
+	ctx, cancel := opContext()
+	defer cancel()
+	count, err := audit.CountDocuments(ctx, filter)
+	if err != nil {
+		writeServerError(w, "Failed to retrieve the audit log", err)
+		return
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(offset).SetLimit(limit)
+	cur, err := audit.Find(ctx, filter, opts)
+	if err != nil {
+		writeServerError(w, "Failed to retrieve the audit log", err)
+		return
+	}
+	defer cur.Close(ctx)
+	entries := []AuditEntry{}
+	if err := cur.All(ctx, &entries); err != nil {
+		writeServerError(w, "Failed to retrieve the audit log", err)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
+	writeJSON(w, entries)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildAuditFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	filter, err := buildAuditFilter(httptest.NewRequest("GET", "/todo/audit?item_id="+id.Hex()+"&operation=delete&since=2024-01-01T00:00:00Z", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if filter["itemId"] != id || filter["operation"] != "delete" || !filter["time"].(bson.M)["$gte"].(time.Time).Equal(since) {
+		t.Fatalf("unexpected filter: %v", filter)
+	}
+	if _, ok := filter["time"].(bson.M)["$lte"]; ok {
+		t.Fatalf("expected no upper bound, got %v", filter)
+	}
+
+	for _, query := range []string{
+		"?item_id=nope",
+		"?operation=read",
+		"?since=yesterday",
+		"?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z",
+	} {
+		if _, err := buildAuditFilter(httptest.NewRequest("GET", "/todo/audit"+query, nil)); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestGetAuditLog(t *testing.T) {
+	collection := setupTestCollection(t)
+	t.Cleanup(func() { auditCollection(collection).Drop(context.TODO()) })
+
+	req := httptest.NewRequest(http.MethodPost, "/todo", strings.NewReader(`{"description": "audited"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(userIDHeader, "alice")
+	rec := httptest.NewRecorder()
+	CreateItem(rec, req)
+	var created TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("create: %d %v", rec.Code, err)
+	}
+	id := created.Id.Hex()
+
+	patch := httptest.NewRequest(http.MethodPatch, "/todo/"+id, strings.NewReader(`{"priority": "high"}`))
+	PatchItem(httptest.NewRecorder(), mux.SetURLVars(patch, map[string]string{"id": id}))
+	del := httptest.NewRequest(http.MethodDelete, "/todo/"+id, nil)
+	DeleteItem(httptest.NewRecorder(), mux.SetURLVars(del, map[string]string{"id": id}))
+
+	rec = httptest.NewRecorder()
+	GetAuditLog(rec, httptest.NewRequest(http.MethodGet, "/todo/audit?item_id="+id, nil))
+	var entries []AuditEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Header().Get("X-Total-Count") != "3" || len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %s: %+v", rec.Header().Get("X-Total-Count"), entries)
+	}
+	if entries[0].Operation != auditDelete || entries[2].Operation != auditCreate || entries[2].Owner != "alice" {
+		t.Fatalf("expected delete, update, create newest first, got %+v", entries)
+	}
+
+	rec = httptest.NewRecorder()
+	GetAuditLog(rec, httptest.NewRequest(http.MethodGet, "/todo/audit?operation=update", nil))
+	entries = nil
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ItemID != created.Id {
+		t.Fatalf("expected the patch only, got %+v", entries)
+	}
+}
+
+func TestMergeItems_Audited(t *testing.T) {
+	collection := setupTestCollection(t)
+	t.Cleanup(func() { auditCollection(collection).Drop(context.TODO()) })
+
+	res, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "buy milk", "completed": false, "tags": []string{"home"}},
+		bson.M{"description": "Buy milk", "completed": false, "tags": []string{"shop"}},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	primary := res.InsertedIDs[0].(primitive.ObjectID)
+	duplicate := res.InsertedIDs[1].(primitive.ObjectID)
+
+	body := `{"primary": "` + primary.Hex() + `", "duplicates": ["` + duplicate.Hex() + `"]}`
+	rec := httptest.NewRecorder()
+	MergeItems(rec, httptest.NewRequest(http.MethodPost, "/todo/merge", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge: %d %s", rec.Code, rec.Body.String())
+	}
+
+	for id, want := range map[primitive.ObjectID]string{primary: auditUpdate, duplicate: auditDelete} {
+		rec = httptest.NewRecorder()
+		GetAuditLog(rec, httptest.NewRequest(http.MethodGet, "/todo/audit?item_id="+id.Hex(), nil))
+		var entries []AuditEntry
+		if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Operation != want {
+			t.Errorf("item %s: expected one %s entry, got %+v", id.Hex(), want, entries)
+		}
+	}
+}
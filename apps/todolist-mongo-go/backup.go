@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// backupTimeFormat is the timestamp suffix of backup collection names. It
+// sorts chronologically and avoids characters that are awkward in names.
+const backupTimeFormat = "20060102T150405Z"
+
+// backupSuffix matches what follows "<collection>_backup_" in a backup name
+var backupSuffix = regexp.MustCompile(`^\d{8}T\d{6}Z$`)
+
+// BackupInfo describes one backup collection
+type BackupInfo struct {
+	Collection string    `json:"collection"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Count      int64     `json:"count"`
+}
+
+// backupPrefix is the name prefix of collection's backups
+func backupPrefix(collection *mongo.Collection) string {
+	return collection.Name() + "_backup_"
+}
+
+// backupTime returns when the backup called name was taken, or false when
+// name is not a backup of collection
+func backupTime(collection *mongo.Collection, name string) (time.Time, bool) {
+	suffix := strings.TrimPrefix(name, backupPrefix(collection))
+	if suffix == name || !backupSuffix.MatchString(suffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, suffix)
+	return t, err == nil
+}
+
+// listBackups returns collection's backups in its database, newest first
+func listBackups(collection *mongo.Collection) ([]BackupInfo, error) {
+	ctx, cancel := opContext()
+	defer cancel()
+	filter := bson.M{"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(backupPrefix(collection))}}
+	names, err := collection.Database().ListCollectionNames(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	for _, name := range names {
+		createdAt, ok := backupTime(collection, name)
+		if !ok {
+			continue
+		}
+		count, err := collection.Database().Collection(name).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, BackupInfo{Collection: name, CreatedAt: createdAt, Count: count})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// BackupCollection copies every document of the todo collection, including
+// soft-deleted ones, into a new <collection>_backup_<timestamp> collection in
+// the same database with an aggregation $out, and answers 201 with the
+// backup's BackupInfo. Indexes are not copied. A second backup within the
+// same second gets 409. Admin: guarded by requireAPIKey.
+func BackupCollection(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	now := time.Now().UTC().Truncate(time.Second)
+	name := backupPrefix(collection) + now.Format(backupTimeFormat)
+
+	ctx, cancel := opContext()
+	defer cancel()
+	existing, err := collection.Database().ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		writeServerError(w, "Failed to list backups", err)
+		return
+	}
+	if len(existing) > 0 {
+		writeErrorResponse(w, http.StatusConflict, "Conflict", "A backup was taken this second; try again")
+		return
+	}
+
+	log.WithFields(log.Fields{"backup": name}).Warn("Backing up TodoItems")
+
+	cur, err := collection.Aggregate(ctx, mongo.Pipeline{{{Key: "$out", Value: name}}})
+	if err != nil {
+		log.Errorf("Failed to back up todo items: %v", err)
+		writeServerError(w, "Failed to back up todo items", err)
+		return
+	}
+	cur.Close(ctx)
+
+	count, err := collection.Database().Collection(name).CountDocuments(ctx, bson.M{})
+	if err != nil {
+		writeServerError(w, "Failed to count backed up todo items", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BackupInfo{Collection: name, CreatedAt: now, Count: count})
+}
+
+// GetBackups lists the todo collection's backups, newest first, with their
+// estimated document counts. Admin: guarded by requireAPIKey.
+func GetBackups(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	backups, err := listBackups(collection)
+	if err != nil {
+		writeServerError(w, "Failed to list backups", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(backups)
+}
+
+// RestoreBackupRequest names the backup to restore
+type RestoreBackupRequest struct {
+	Collection string `json:"collection"`
+}
+
+// RestoreBackup replaces the contents of the todo collection with those of
+// one of its backups, running an aggregation $out from the backup onto the
+// collection. $out swaps the data in only once the copy is complete and keeps
+// the collection's indexes, so a failure (such as backed up documents
+// breaking a unique index created since) leaves the current items untouched.
+// Items written after the backup are lost; take a fresh backup first to keep
+// an undo point. Admin: guarded by requireAPIKey, and requires
+// confirm=restore and a backup name of this collection that exists.
+func RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	if r.URL.Query().Get("confirm") != "restore" {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Restore requires confirm=restore")
+		return
+	}
+	var req RestoreBackupRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	if _, ok := backupTime(collection, req.Collection); !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "collection must be a backup name like "+backupPrefix(collection)+backupTimeFormat)
+		return
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	existing, err := collection.Database().ListCollectionNames(ctx, bson.M{"name": req.Collection})
+	if err != nil {
+		writeServerError(w, "Failed to list backups", err)
+		return
+	}
+	if len(existing) == 0 {
+		writeErrorResponse(w, http.StatusNotFound, "Not Found", "Backup not found")
+		return
+	}
+
+	log.WithFields(log.Fields{"backup": req.Collection}).Warn("Restoring TodoItems from backup")
+
+	// $out reports no per-document changes, so the audit entries come from
+	// comparing the item IDs before and after
+	before, err := itemIDs(ctx, collection)
+	if err != nil {
+		writeServerError(w, "Failed to list the current todo items", err)
+		return
+	}
+
+	backup := collection.Database().Collection(req.Collection)
+	cur, err := backup.Aggregate(ctx, mongo.Pipeline{{{Key: "$out", Value: collection.Name()}}})
+	if err != nil {
+		log.Errorf("Failed to restore todo items from %s: %v", req.Collection, err)
+		writeServerError(w, "Failed to restore backup", err)
+		return
+	}
+	cur.Close(ctx)
+
+	restored, err := itemIDs(ctx, collection)
+	if err != nil {
+		writeServerError(w, "Failed to count restored todo items", err)
+		return
+	}
+	kept := make(map[primitive.ObjectID]bool, len(restored))
+	for _, id := range restored {
+		kept[id] = true
+	}
+	var removed []primitive.ObjectID
+	for _, id := range before {
+		if !kept[id] {
+			removed = append(removed, id)
+		}
+	}
+	recordAudit(r, collection, auditUpdate, restored...)
+	recordAudit(r, collection, auditDelete, removed...)
+	count := len(restored)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"restored": req.Collection, "count": count})
+}
+
+// itemIDs lists the _id of every document in collection, soft-deleted ones
+// included
+func itemIDs(ctx context.Context, collection *mongo.Collection) ([]primitive.ObjectID, error) {
+	values, err := collection.Distinct(ctx, "_id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
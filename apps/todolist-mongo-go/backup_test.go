@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBackupTime(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	collection := client.Database("todolist").Collection("TodoItemModel")
+
+	got, ok := backupTime(collection, "TodoItemModel_backup_20240102T030405Z")
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Fatalf("got %v, %v; want %v", got, ok, want)
+	}
+	for _, name := range []string{
+		"TodoItemModel",
+		"TodoItemModel_backup_",
+		"TodoItemModel_backup_20240102",
+		"TodoItemModel_backup_20241302T030405Z",
+		"TodoItemModel_backup_20240102T030405Z.x",
+		"Other_backup_20240102T030405Z",
+	} {
+		if _, ok := backupTime(collection, name); ok {
+			t.Errorf("%q should not be a backup name", name)
+		}
+	}
+}
+
+func TestBackupCollection(t *testing.T) {
+	collection := setupTestCollection(t)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "one", "completed": false},
+		bson.M{"description": "two", "completed": true},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	BackupCollection(rec, httptest.NewRequest(http.MethodPost, "/todo/backup", nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var backup BackupInfo
+	if err := json.NewDecoder(rec.Body).Decode(&backup); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	t.Cleanup(func() { collection.Database().Collection(backup.Collection).Drop(context.TODO()) })
+	if backup.Count != 2 {
+		t.Fatalf("expected 2 documents backed up, got %d", backup.Count)
+	}
+
+	rec = httptest.NewRecorder()
+	GetBackups(rec, httptest.NewRequest(http.MethodGet, "/todo/backups", nil))
+	var backups []BackupInfo
+	if err := json.NewDecoder(rec.Body).Decode(&backups); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Collection != backup.Collection {
+		t.Fatalf("expected the new backup to be listed, got %+v", backups)
+	}
+}
+
+func TestRestoreBackup_Validation(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	prev := tododb
+	defer func() { tododb = prev }()
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+
+	tests := []struct {
+		query string
+		body  string
+	}{
+		{"", `{"collection":"TodoItemModel_backup_20240102T030405Z"}`},
+		{"?confirm=restore", `{"collection":"TodoItemModel"}`},
+		{"?confirm=restore", `{"collection":"users_backup_20240102T030405Z"}`},
+		{"?confirm=restore", `{"collection":"TodoItemModel_backup_latest"}`},
+		{"?confirm=restore", `{"name":"TodoItemModel_backup_20240102T030405Z"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		RestoreBackup(rec, httptest.NewRequest(http.MethodPost, "/todo/restore-backup"+tt.query, strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d: %s", tt.query, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	collection := setupTestCollection(t)
+	if _, err := collection.InsertOne(context.TODO(), bson.M{"description": "before", "completed": false}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	BackupCollection(rec, httptest.NewRequest(http.MethodPost, "/todo/backup", nil))
+	var backup BackupInfo
+	if err := json.NewDecoder(rec.Body).Decode(&backup); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	t.Cleanup(func() { collection.Database().Collection(backup.Collection).Drop(context.TODO()) })
+
+	after, err := collection.InsertOne(context.TODO(), bson.M{"description": "after", "completed": false})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	t.Cleanup(func() { auditCollection(collection).Drop(context.TODO()) })
+
+	rec = httptest.NewRecorder()
+	body := strings.NewReader(`{"collection":"` + backup.Collection + `"}`)
+	RestoreBackup(rec, httptest.NewRequest(http.MethodPost, "/todo/restore-backup?confirm=restore", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n, _ := collection.CountDocuments(context.TODO(), bson.M{}); n != 1 {
+		t.Fatalf("expected only the backed up item after restoring, got %d items", n)
+	}
+	removed := bson.M{"itemId": after.InsertedID, "operation": auditDelete}
+	if n, _ := auditCollection(collection).CountDocuments(context.TODO(), removed); n != 1 {
+		t.Fatalf("expected a delete audit entry for the item the restore removed, got %d", n)
+	}
+
+	rec = httptest.NewRecorder()
+	body = strings.NewReader(`{"collection":"` + backupPrefix(collection) + `20000101T000000Z"}`)
+	RestoreBackup(rec, httptest.NewRequest(http.MethodPost, "/todo/restore-backup?confirm=restore", body))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing backup, got %d", rec.Code)
+	}
+}
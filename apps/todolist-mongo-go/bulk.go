@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBulkItems caps the number of IDs accepted by a single bulk request
+const maxBulkItems = 100
+
+// Import limits for POST /todo/bulk and /todo/import.csv, configured from
+// MAX_IMPORT_ITEMS and MAX_IMPORT_BYTES in main. Both are enforced while the
+// body is read, before anything is inserted.
+var maxImportItems = 100
+var maxImportBytes int64 = 1 << 20
+
+// errTooManyItems is returned by decodeImportItems past maxImportItems
+var errTooManyItems = errors.New("too many items")
+
+// decodeImportItems stream-decodes a JSON array of NewTodoItem one element at
+// a time, so memory stays bounded by maxImportItems rather than by whatever
+// the client sends. It stops with errTooManyItems as soon as the array holds
+// more than maxImportItems entries; r.Body must already be limited to
+// maxImportBytes.
+func decodeImportItems(r *http.Request) ([]NewTodoItem, error) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if token, err := decoder.Token(); err != nil {
+		return nil, jsonBodyError(err)
+	} else if token != json.Delim('[') {
+		return nil, fmt.Errorf("invalid JSON body: expected an array of items")
+	}
+
+	items := []NewTodoItem{}
+	for decoder.More() {
+		if len(items) == maxImportItems {
+			return nil, errTooManyItems
+		}
+		var item NewTodoItem
+		if err := decoder.Decode(&item); err != nil {
+			return nil, jsonBodyError(err)
+		}
+		items = append(items, item)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, jsonBodyError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON body: unexpected data after the array")
+	}
+	return items, nil
+}
+
+// NewTodoItem is the JSON shape of an item to be created
+type NewTodoItem struct {
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	Priority    string     `json:"priority,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DueDate     *time.Time `json:"dueDate,omitempty"`
+}
+
+// isBlankDescription reports whether s has no visible content: it is empty or
+// made only of whitespace, zero-width/format characters (U+200B, U+FEFF, ...)
+// and variation selectors. Emoji are visible, so emoji-only descriptions are
+// allowed.
+func isBlankDescription(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) && !unicode.In(r, unicode.Cf, unicode.Variation_Selector) {
+			return false
+		}
+	}
+	return true
+}
+
+// descriptionKey normalizes a description for comparison: lowercased,
+// trimmed and with runs of whitespace collapsed to one space, so "Buy  Milk "
+// and "buy milk" share a key
+func descriptionKey(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// validate applies the same rules as the single-item create and patch paths,
+// returning the first problem found by fieldErrors
+func (n *NewTodoItem) validate() error {
+	if errs := n.fieldErrors(); len(errs) > 0 {
+		return errors.New(errs[0].Message)
+	}
+	return nil
+}
+
+// fieldErrors lists every validation problem of n, at most one per field
+func (n *NewTodoItem) fieldErrors() []FieldError {
+	var errs []FieldError
+	if isBlankDescription(n.Description) {
+		errs = append(errs, FieldError{Field: "description", Message: "description cannot be empty"})
+	} else if err := validateDescriptionSize(n.Description); err != nil {
+		errs = append(errs, FieldError{Field: "description", Message: err.Error()})
+	}
+	if n.Priority != "" && !validPriorities[n.Priority] {
+		errs = append(errs, FieldError{Field: "priority", Message: "priority must be one of low, medium, high"})
+	}
+	if err := validateTags(n.Tags); err != nil {
+		errs = append(errs, FieldError{Field: "tags", Message: err.Error()})
+	}
+	if n.DueDate != nil && n.DueDate.IsZero() {
+		errs = append(errs, FieldError{Field: "dueDate", Message: "dueDate must be a valid RFC3339 time"})
+	}
+	return errs
+}
+
+// toModel builds the document to insert, stamped with now
+func (n *NewTodoItem) toModel(owner string, now time.Time) *TodoItemModel {
+	var completedAt *time.Time
+	if n.Completed {
+		completedAt = &now
+	}
+	return &TodoItemModel{
+		Description:    n.Description,
+		DescriptionKey: descriptionKey(n.Description),
+		Completed:      n.Completed,
+		Status:         statusFor(n.Completed),
+		Priority:       n.Priority,
+		Tags:           n.Tags,
+		DueDate:        n.DueDate,
+		Owner:          owner,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		CompletedAt:    completedAt,
+	}
+}
+
+// contentHash identifies an imported record by its normalized description
+// and owner. The NUL separator keeps ("ab", "c") and ("a", "bc") apart.
+func contentHash(description, owner string) string {
+	sum := sha256.Sum256([]byte(descriptionKey(description) + "\x00" + owner))
+	return hex.EncodeToString(sum[:])
+}
+
+// existingContentHashes returns the content hashes of live items owned by
+// owner whose description matches one of descriptions after normalization.
+// Items written before descriptionKey existed are matched on the exact
+// description until the migration backfills their key. Hashes are computed from
+// the stored fields rather than kept on the documents, so there is nothing to
+// keep in sync when an item is edited or transferred; the cost is one query
+// per import on description/owner, which is a collection scan without an
+// index on those fields. A stored, indexed hash would turn that into an index
+// lookup but must be rewritten by every update that touches either field.
+func existingContentHashes(ctx context.Context, collection *mongo.Collection, owner string, descriptions []string) (map[string]bool, error) {
+	keys := make([]string, len(descriptions))
+	for i, description := range descriptions {
+		keys[i] = descriptionKey(description)
+	}
+	filter := notDeleted(bson.M{"$or": bson.A{
+		bson.M{"descriptionKey": bson.M{"$in": keys}},
+		bson.M{"descriptionKey": bson.M{"$exists": false}, "description": bson.M{"$in": descriptions}},
+	}})
+	if owner == "" {
+		filter["owner"] = bson.M{"$in": bson.A{nil, ""}}
+	} else {
+		filter["owner"] = owner
+	}
+	opts := options.Find().SetProjection(bson.M{"description": 1, "owner": 1})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	hashes := map[string]bool{}
+	for cur.Next(ctx) {
+		var item TodoItemModel
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		hashes[contentHash(item.Description, item.Owner)] = true
+	}
+	return hashes, cur.Err()
+}
+
+// BulkCreateResult is returned by POST /todo/bulk?dedupe=true
+type BulkCreateResult struct {
+	Created []*TodoItemModel `json:"created"`
+	Skipped int              `json:"skipped"`
+}
+
+// CreateItemsBulk inserts a JSON array of items. All items are validated
+// before anything is written, and the insert runs in a transaction when the
+// deployment supports one so the batch is all-or-nothing.
+//
+// With dedupe=true, items whose description and owner match a live item (or
+// an earlier entry in the same batch) are skipped, so a retried import does
+// not create duplicates. The response then reports the created items and the
+// number skipped.
+func CreateItemsBulk(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	dedupe := false
+	if value := r.URL.Query().Get("dedupe"); value != "" {
+		var err error
+		if dedupe, err = strconv.ParseBool(value); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid dedupe value. Must be true or false")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	items, err := decodeImportItems(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", fmt.Sprintf("Request body exceeds %d bytes", maxImportBytes))
+		case err == errTooManyItems:
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("At most %d items can be created at once", maxImportItems))
+		default:
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		}
+		return
+	}
+	if len(items) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "At least one item is required")
+		return
+	}
+
+	owner := ownerFromRequest(r)
+	now := time.Now().UTC()
+	descriptions := make([]string, len(items))
+	for i := range items {
+		if err := items[i].validate(); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("Item %d: %v", i, err))
+			return
+		}
+		descriptions[i] = items[i].Description
+	}
+
+	if !checkOwnerQuota(w, collection, owner, len(items)) {
+		return
+	}
+
+	log.WithFields(log.Fields{"count": len(items), "dedupe": dedupe, "transaction": transactionsSupported}).Info("Bulk creating TodoItems")
+
+	var todos []*TodoItemModel
+	skipped := 0
+	ctx, cancel := opContext()
+	defer cancel()
+	err = withTransaction(ctx, func(ctx context.Context) error {
+		seen := map[string]bool{}
+		if dedupe {
+			var err error
+			if seen, err = existingContentHashes(ctx, collection, owner, descriptions); err != nil {
+				return err
+			}
+		}
+
+		todos, skipped = nil, 0
+		var docs []interface{}
+		for i := range items {
+			if dedupe {
+				hash := contentHash(items[i].Description, owner)
+				if seen[hash] {
+					skipped++
+					continue
+				}
+				seen[hash] = true
+			}
+			todo := items[i].toModel(owner, now)
+			todos = append(todos, todo)
+			docs = append(docs, todo)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		result, err := collection.InsertMany(ctx, docs)
+		if err != nil {
+			return err
+		}
+		for i, id := range result.InsertedIDs {
+			todos[i].Id = id.(primitive.ObjectID)
+		}
+		return nil
+	})
+	if isDuplicateDescription(err) {
+		writeErrorResponse(w, http.StatusConflict, "Conflict", "A todo item with one of these descriptions already exists")
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to bulk insert todo items: %v", err)
+		writeServerError(w, "Failed to create todo items", err)
+		return
+	}
+	if todos == nil {
+		todos = []*TodoItemModel{}
+	}
+	ids := make([]primitive.ObjectID, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.Id
+	}
+	recordAudit(r, collection, auditCreate, ids...)
+
+	w.Header().Set("Content-Type", "application/json")
+	if dedupe {
+		if skipped > 0 {
+			log.Infof("Skipped %d duplicate items", skipped)
+		}
+		json.NewEncoder(w).Encode(BulkCreateResult{Created: todos, Skipped: skipped})
+		return
+	}
+	json.NewEncoder(w).Encode(todos)
+}
+
+// BulkIDsRequest is the JSON body of the bulk complete and delete endpoints
+type BulkIDsRequest struct {
+	IDs       []string `json:"ids"`
+	Completed *bool    `json:"completed,omitempty"`
+}
+
+// BulkResult reports the effective size and outcome of a bulk operation.
+// Unique is the number of distinct IDs after duplicates were removed.
+type BulkResult struct {
+	Requested int   `json:"requested"`
+	Unique    int   `json:"unique"`
+	Matched   int64 `json:"matched"`
+	Modified  int64 `json:"modified"`
+}
+
+// parseObjectIDs converts hex IDs to ObjectIDs, dropping duplicates while
+// keeping the first-seen order
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", id)
+		}
+		if seen[objID] {
+			continue
+		}
+		seen[objID] = true
+		objIDs = append(objIDs, objID)
+	}
+	return objIDs, nil
+}
+
+// decodeBulkIDs reads a BulkIDsRequest and its de-duplicated ObjectIDs,
+// writing a 400 and returning ok=false when the body is invalid or lists no
+// IDs
+func decodeBulkIDs(w http.ResponseWriter, r *http.Request) (req BulkIDsRequest, objIDs []primitive.ObjectID, ok bool) {
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return req, nil, false
+	}
+	if len(req.IDs) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "ids must list at least one ID")
+		return req, nil, false
+	}
+	if len(req.IDs) > maxBulkItems {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("At most %d IDs can be sent at once", maxBulkItems))
+		return req, nil, false
+	}
+	objIDs, err := parseObjectIDs(req.IDs)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return req, nil, false
+	}
+	return req, objIDs, true
+}
+
+// BulkUpdateCompleted sets the completed state (default true) on every listed item
+func BulkUpdateCompleted(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	req, objIDs, ok := decodeBulkIDs(w, r)
+	if !ok {
+		return
+	}
+	completed := true
+	if req.Completed != nil {
+		completed = *req.Completed
+	}
+
+	log.WithFields(log.Fields{"count": len(objIDs), "completed": completed}).Info("Bulk updating TodoItems")
+
+	filter := notDeleted(bson.M{"_id": bson.M{"$in": objIDs}})
+	now := time.Now().UTC()
+	update := bumpVersion(completionUpdate(bson.M{"updatedAt": now}, completed, now))
+	ctx, cancel := opContext()
+	defer cancel()
+	res, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Errorf("Failed to bulk update todo items: %v", err)
+		writeServerError(w, "Failed to update todo items", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		recordBulkAudit(r, collection, auditUpdate, bson.M{"_id": bson.M{"$in": objIDs}}, now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BulkResult{
+		Requested: len(req.IDs),
+		Unique:    len(objIDs),
+		Matched:   res.MatchedCount,
+		Modified:  res.ModifiedCount,
+	})
+}
+
+// BulkDelete soft-deletes every listed item
+func BulkDelete(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	req, objIDs, ok := decodeBulkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	log.WithFields(log.Fields{"count": len(objIDs)}).Info("Bulk deleting TodoItems")
+
+	now := time.Now().UTC()
+	filter := notDeleted(bson.M{"_id": bson.M{"$in": objIDs}})
+	update := bumpVersion(bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}})
+	ctx, cancel := opContext()
+	defer cancel()
+	res, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Errorf("Failed to bulk delete todo items: %v", err)
+		writeServerError(w, "Failed to delete todo items", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		recordBulkAudit(r, collection, auditDelete, bson.M{"_id": bson.M{"$in": objIDs}}, now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BulkResult{
+		Requested: len(req.IDs),
+		Unique:    len(objIDs),
+		Matched:   res.MatchedCount,
+		Modified:  res.ModifiedCount,
+	})
+}
+
+// BulkPreview counts the live items a bulk complete or delete with the same
+// body would match, without changing anything. Modified is always 0.
+func BulkPreview(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	req, objIDs, ok := decodeBulkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	log.WithFields(log.Fields{"count": len(objIDs)}).Info("Previewing bulk operation")
+
+	count, err := countTodoItems(collection, notDeleted(bson.M{"_id": bson.M{"$in": objIDs}}))
+	if err != nil {
+		writeServerError(w, "Failed to count todo items", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BulkResult{
+		Requested: len(req.IDs),
+		Unique:    len(objIDs),
+		Matched:   count,
+	})
+}
+
+// maxOwnerLength caps the length of an owner identifier
+const maxOwnerLength = 128
+
+// validateOwnerID checks an owner identifier given in a request body
+func validateOwnerID(field, owner string) error {
+	if owner == "" || strings.TrimSpace(owner) != owner {
+		return fmt.Errorf("%s must be a non-empty user ID without surrounding whitespace", field)
+	}
+	if len(owner) > maxOwnerLength {
+		return fmt.Errorf("%s must be at most %d characters", field, maxOwnerLength)
+	}
+	for _, r := range owner {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s must not contain control characters", field)
+		}
+	}
+	return nil
+}
+
+// TransferRequest moves items from one owner to another. When IDs is empty
+// every item owned by From is moved.
+type TransferRequest struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	IDs  []string `json:"ids,omitempty"`
+}
+
+// TransferItems reassigns items from one owner to another. Only items
+// currently owned by From are changed, so IDs belonging to someone else are
+// counted as requested but not moved. Omitting ids moves all of From's
+// items; an empty ids array is rejected rather than read as either.
+func TransferItems(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	var req TransferRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	if err := validateOwnerID("from", req.From); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	if err := validateOwnerID("to", req.To); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	if req.From == req.To {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "from and to must differ")
+		return
+	}
+	if len(req.IDs) > maxBulkItems {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("At most %d IDs can be sent at once", maxBulkItems))
+		return
+	}
+
+	if req.IDs != nil && len(req.IDs) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "ids must list at least one ID; omit it to transfer all of from's items")
+		return
+	}
+
+	filter := notDeleted(bson.M{"owner": req.From})
+	// the transferred items are the ones now owned by to and written by
+	// this request
+	touched := bson.M{"owner": req.To}
+	if req.IDs != nil {
+		objIDs, err := parseObjectIDs(req.IDs)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		filter["_id"] = bson.M{"$in": objIDs}
+		touched["_id"] = bson.M{"$in": objIDs}
+	}
+
+	log.WithFields(log.Fields{"from": req.From, "to": req.To, "ids": len(req.IDs)}).Info("Transferring TodoItems")
+
+	ctx, cancel := opContext()
+	defer cancel()
+	now := time.Now().UTC()
+	update := bumpVersion(bson.M{"$set": bson.M{"owner": req.To, "updatedAt": now}})
+	res, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Errorf("Failed to transfer todo items: %v", err)
+		writeServerError(w, "Failed to transfer todo items", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		recordBulkAudit(r, collection, auditUpdate, touched, now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"moved": res.ModifiedCount})
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseObjectIDs_RemovesDuplicates(t *testing.T) {
+	ids := []string{
+		"507f1f77bcf86cd799439011",
+		"507f1f77bcf86cd799439012",
+		"507f1f77bcf86cd799439011",
+		"507F1F77BCF86CD799439012",
+	}
+
+	objIDs, err := parseObjectIDs(ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objIDs) != 2 {
+		t.Fatalf("expected 2 unique IDs, got %d: %v", len(objIDs), objIDs)
+	}
+	if objIDs[0].Hex() != ids[0] || objIDs[1].Hex() != ids[1] {
+		t.Fatalf("expected first-seen order, got %v", objIDs)
+	}
+}
+
+func TestParseObjectIDs_InvalidID(t *testing.T) {
+	if _, err := parseObjectIDs([]string{"507f1f77bcf86cd799439011", "nope"}); err == nil {
+		t.Fatal("expected an error for an invalid ID")
+	}
+}
+
+func TestNewTodoItem_BlankDescription(t *testing.T) {
+	cases := []struct {
+		name        string
+		description string
+		wantErr     bool
+	}{
+		{"empty", "", true},
+		{"spaces", "   ", true},
+		{"tabs and newlines", "\t\n\r ", true},
+		{"non-breaking space", "\u00a0", true},
+		{"zero-width space", "\u200b\u200b", true},
+		{"zero-width joiner and BOM", " \u200d\ufeff ", true},
+		{"lone variation selector", "\ufe0f", true},
+		{"text", "buy milk", false},
+		{"padded text", "  buy milk\u200b", false},
+		{"emoji", "\U0001F95B", false},
+		{"emoji sequence", "\U0001F469\u200d\U0001F4BB", false},
+		{"emoji with selector", "\u2764\ufe0f", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			item := NewTodoItem{Description: c.description}
+			err := item.validate()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("validate(%q) error = %v, wantErr %v", c.description, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOwnerID(t *testing.T) {
+	for _, owner := range []string{"alice", "bob@example.com", "user-42"} {
+		if err := validateOwnerID("from", owner); err != nil {
+			t.Errorf("validateOwnerID(%q) = %v, want nil", owner, err)
+		}
+	}
+	for _, owner := range []string{"", " alice", "alice\n", "a\x00b", strings.Repeat("x", maxOwnerLength+1)} {
+		if err := validateOwnerID("from", owner); err == nil {
+			t.Errorf("validateOwnerID(%q) = nil, want error", owner)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if contentHash("buy milk", "alice") != contentHash("buy milk", "alice") {
+		t.Fatal("hash is not stable")
+	}
+	if contentHash("buy milk", "alice") == contentHash("buy milk", "bob") {
+		t.Fatal("owner is not part of the hash")
+	}
+	if contentHash("ab", "c") == contentHash("a", "bc") {
+		t.Fatal("description and owner are not separated")
+	}
+	if contentHash("Buy  Milk ", "alice") != contentHash("buy milk", "alice") {
+		t.Fatal("hash should ignore case and whitespace differences")
+	}
+}
+
+func TestDescriptionKey(t *testing.T) {
+	tests := map[string]string{
+		"Buy Milk":              "buy milk",
+		"  buy\t milk\n":        "buy milk",
+		"BUY   MILK":            "buy milk",
+		"Überweisung erledigen": "überweisung erledigen",
+		"":                      "",
+	}
+	for in, want := range tests {
+		if got := descriptionKey(in); got != want {
+			t.Errorf("descriptionKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDecodeImportItems(t *testing.T) {
+	prevItems := maxImportItems
+	defer func() { maxImportItems = prevItems }()
+	maxImportItems = 2
+
+	tests := []struct {
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{`[]`, 0, false},
+		{`[{"description":"a"},{"description":"b"}]`, 2, false},
+		{`[{"description":"a"},{"description":"b"},{"description":"c"}]`, 0, true},
+		{`{"description":"a"}`, 0, true},
+		{`[{"description":"a"}`, 0, true},
+		{`[{"description":"a"}] []`, 0, true},
+		{`[{"description":"a","bogus":1}]`, 0, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/todo/bulk", strings.NewReader(tt.body))
+		items, err := decodeImportItems(req)
+		if (err != nil) != tt.wantErr || len(items) != tt.want {
+			t.Errorf("%s: got %d items, err %v", tt.body, len(items), err)
+		}
+	}
+}
+
+func TestCreateItemsBulk_ImportLimits(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	prev, prevItems, prevBytes := tododb, maxImportItems, maxImportBytes
+	defer func() { tododb, maxImportItems, maxImportBytes = prev, prevItems, prevBytes }()
+	// nothing may reach this unconnected collection
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+	maxImportItems, maxImportBytes = 3, 256
+
+	var many []string
+	for i := 0; i < 4; i++ {
+		many = append(many, fmt.Sprintf(`{"description":"item %d"}`, i))
+	}
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`[{"description":"` + strings.Repeat("x", 300) + `"}]`, http.StatusRequestEntityTooLarge},
+		{"[" + strings.Repeat(" ", 300) + "]", http.StatusRequestEntityTooLarge},
+		{"[" + strings.Join(many, ",") + "]", http.StatusBadRequest},
+		{`{"description":"not an array"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/todo/bulk", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		CreateItemsBulk(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%.40s...: got %d, want %d: %s", tt.body, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestBulkEndpoints_EmptyInput(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	prev := tododb
+	defer func() { tododb = prev }()
+	// nothing may reach this unconnected collection
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"create", CreateItemsBulk, `[]`},
+		{"create whitespace", CreateItemsBulk, ` [ ] `},
+		{"complete", BulkUpdateCompleted, `{"ids": []}`},
+		{"complete without ids", BulkUpdateCompleted, `{}`},
+		{"delete", BulkDelete, `{"ids": []}`},
+		{"delete without ids", BulkDelete, `{"ids": null}`},
+		{"preview", BulkPreview, `{"ids": []}`},
+		{"transfer", TransferItems, `{"from": "alice", "to": "bob", "ids": []}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodPost, "/todo/bulk", strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBulkPreview(t *testing.T) {
+	collection := setupTestCollection(t)
+	res, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "one", "completed": false},
+		bson.M{"description": "two", "completed": false},
+		bson.M{"description": "gone", "completed": false, "deletedAt": time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var ids []string
+	for _, id := range res.InsertedIDs {
+		ids = append(ids, id.(primitive.ObjectID).Hex())
+	}
+	ids = append(ids, ids[0], primitive.NewObjectID().Hex())
+	body, _ := json.Marshal(BulkIDsRequest{IDs: ids})
+
+	rec := httptest.NewRecorder()
+	BulkPreview(rec, httptest.NewRequest(http.MethodPost, "/todo/bulk/preview", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result BulkResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := BulkResult{Requested: 5, Unique: 4, Matched: 2}
+	if result != want {
+		t.Fatalf("got %+v, want %+v", result, want)
+	}
+	if n, _ := collection.CountDocuments(context.TODO(), bson.M{"deletedAt": nil}); n != 2 {
+		t.Fatalf("preview must not change anything, %d live items left", n)
+	}
+}
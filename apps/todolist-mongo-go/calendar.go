@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// icsTimeFormat is the RFC 5545 UTC DATE-TIME form
+const icsTimeFormat = "20060102T150405Z"
+
+// icsMaxLine is the longest content line RFC 5545 allows, in octets,
+// excluding the CRLF
+const icsMaxLine = 75
+
+// icsPriorities maps item priorities onto the iCalendar 1 (highest) to 9
+// (lowest) scale
+var icsPriorities = map[string]string{"high": "1", "medium": "5", "low": "9"}
+
+// icsEscape escapes a TEXT property value
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// icsFold splits a content line into lines of at most icsMaxLine octets, each
+// continuation starting with a space, without cutting a UTF-8 sequence
+func icsFold(line string) string {
+	var b strings.Builder
+	limit := icsMaxLine
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space counts towards the next line's length
+		limit = icsMaxLine - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// writeCalendar writes items as an iCalendar VTODO feed. UIDs are derived
+// from the ObjectIDs so calendar apps recognize an item across refreshes.
+func writeCalendar(w io.Writer, items []*TodoItemModel, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		bw.WriteString(icsFold(name + ":" + value))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//mig-demo-apps//todolist-mongo-go//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", "Todo List")
+	for _, item := range items {
+		stamp := item.UpdatedAt
+		if stamp.IsZero() {
+			stamp = now
+		}
+		line("BEGIN", "VTODO")
+		line("UID", item.Id.Hex()+"@todolist-mongo-go")
+		line("DTSTAMP", stamp.UTC().Format(icsTimeFormat))
+		if !item.CreatedAt.IsZero() {
+			line("CREATED", item.CreatedAt.UTC().Format(icsTimeFormat))
+		}
+		if !item.UpdatedAt.IsZero() {
+			line("LAST-MODIFIED", item.UpdatedAt.UTC().Format(icsTimeFormat))
+		}
+		line("SUMMARY", icsEscape(item.Description))
+		if item.DueDate != nil {
+			line("DUE", item.DueDate.UTC().Format(icsTimeFormat))
+		}
+		switch item.Status {
+		case statusInProgress:
+			line("STATUS", "IN-PROCESS")
+		case statusAbandoned:
+			line("STATUS", "CANCELLED")
+		default:
+			line("STATUS", "NEEDS-ACTION")
+		}
+		if priority, ok := icsPriorities[item.Priority]; ok {
+			line("PRIORITY", priority)
+		}
+		if len(item.Tags) > 0 {
+			tags := make([]string, len(item.Tags))
+			for i, tag := range item.Tags {
+				tags[i] = icsEscape(tag)
+			}
+			line("CATEGORIES", strings.Join(tags, ","))
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// GetCalendar serves the incomplete items that have a due date as an
+// iCalendar (RFC 5545) feed of VTODO entries, soonest due first, so a
+// calendar app can subscribe to the list.
+func GetCalendar(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	log.Info("Get TodoItems as iCalendar")
+
+	filter := notDeleted(actionable(bson.M{"dueDate": bson.M{"$ne": nil}}))
+	items, err := findTodoItems(collection, filter, options.Find().SetSort(bson.M{"dueDate": 1}))
+	if err != nil {
+		writeServerError(w, "Failed to retrieve todo items", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="todos.ics"`)
+	if err := writeCalendar(w, items, time.Now()); err != nil {
+		log.Errorf("Failed to write calendar: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIcsEscape(t *testing.T) {
+	got := icsEscape("Buy milk, eggs; bread\\butter\nthen cook")
+	want := `Buy milk\, eggs\; bread\\butter\nthen cook`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestIcsFold(t *testing.T) {
+	for _, line := range []string{
+		"SUMMARY:" + strings.Repeat("a", 200),
+		"SUMMARY:" + strings.Repeat("買", 60),
+		"SUMMARY:" + strings.Repeat("🎉", 40),
+	} {
+		folded := icsFold(line)
+		for i, part := range strings.Split(folded, "\r\n") {
+			if len(part) > icsMaxLine {
+				t.Errorf("line %d is %d octets: %q", i, len(part), part)
+			}
+			if i > 0 && !strings.HasPrefix(part, " ") {
+				t.Errorf("continuation line %d does not start with a space", i)
+			}
+			if !utf8.ValidString(part) {
+				t.Errorf("line %d splits a UTF-8 sequence", i)
+			}
+		}
+		if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolding gives %q, want %q", unfolded, line)
+		}
+	}
+	if got := icsFold("SUMMARY:short"); got != "SUMMARY:short" {
+		t.Errorf("short line changed: %q", got)
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65a1b2c3d4e5f60718293a4b")
+	due := time.Date(2024, 3, 1, 17, 0, 0, 0, time.FixedZone("CET", 3600))
+	updated := time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)
+	items := []*TodoItemModel{{
+		Id: id, Description: "Pay rent, on time", Priority: "high", Tags: []string{"home"},
+		DueDate: &due, CreatedAt: updated, UpdatedAt: updated, Status: statusInProgress,
+	}}
+
+	var buf bytes.Buffer
+	if err := writeCalendar(&buf, items, time.Now()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:65a1b2c3d4e5f60718293a4b@todolist-mongo-go\r\n",
+		"DTSTAMP:20240201T093000Z\r\n",
+		"DUE:20240301T160000Z\r\n",
+		"SUMMARY:Pay rent\\, on time\r\n",
+		"STATUS:IN-PROCESS\r\n",
+		"PRIORITY:1\r\n",
+		"CATEGORIES:home\r\n",
+		"END:VTODO\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("lines must end in CRLF")
+	}
+}
+
+func TestWriteCalendar_Abandoned(t *testing.T) {
+	due := time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC)
+	items := []*TodoItemModel{{Id: primitive.NewObjectID(), Description: "dropped", DueDate: &due, Status: statusAbandoned}}
+
+	var buf bytes.Buffer
+	if err := writeCalendar(&buf, items, time.Now()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "STATUS:CANCELLED\r\n") {
+		t.Errorf("abandoned items should be CANCELLED, got:\n%s", out)
+	}
+}
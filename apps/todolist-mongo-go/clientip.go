@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// trustedProxies are the networks whose forwarding headers are believed.
+// Configured from TRUSTED_PROXIES in main; empty means no proxy is trusted.
+var trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses CIDRs, accepting bare IPs as single hosts
+func parseTrustedProxies(values []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// isTrustedProxy reports whether ip falls in one of the trustedProxies
+func isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP of the direct peer from r.RemoteAddr
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// resolveClientIP works out the real client address. Forwarding headers are
+// only used when the direct peer is a trusted proxy: X-Forwarded-For is read
+// right to left, skipping trusted proxies, and the first other address is the
+// client; X-Real-IP is the fallback. An entry that is not a valid IP stops
+// the walk so a spoofed value can't be picked.
+func resolveClientIP(r *http.Request) net.IP {
+	peer := remoteIP(r)
+	if peer == nil || !isTrustedProxy(peer) {
+		return peer
+	}
+
+	client := peer
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip
+			if !isTrustedProxy(ip) {
+				break
+			}
+		}
+		return client
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+	return client
+}
+
+type clientIPKey struct{}
+
+// clientIPMiddleware records the resolved client IP on the request context
+// for clientIP. Forwarding headers from untrusted peers are removed so later
+// handlers can't mistake them for trusted values.
+func clientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := resolveClientIP(r)
+		if peer := remoteIP(r); peer == nil || !isTrustedProxy(peer) {
+			r.Header.Del("X-Forwarded-For")
+			r.Header.Del("X-Real-IP")
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// clientIP returns the client address resolved by clientIPMiddleware, falling
+// back to the direct peer
+func clientIP(r *http.Request) string {
+	ip, ok := r.Context().Value(clientIPKey{}).(net.IP)
+	if !ok || ip == nil {
+		ip = remoteIP(r)
+	}
+	if ip == nil {
+		return r.RemoteAddr
+	}
+	return ip.String()
+}
+
+// loadTrustedProxies reads TRUSTED_PROXIES; invalid entries are fatal
+func loadTrustedProxies() {
+	values := getEnvList("TRUSTED_PROXIES", nil)
+	nets, err := parseTrustedProxies(values)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES %q: %v", strings.Join(values, ","), err)
+	}
+	trustedProxies = nets
+	if len(nets) > 0 {
+		log.Infof("Trusting forwarding headers from %v", values)
+	}
+}
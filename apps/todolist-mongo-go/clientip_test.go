@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	prev := trustedProxies
+	defer func() { trustedProxies = prev }()
+	var err error
+	trustedProxies, err = parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"direct client ignores headers", "203.0.113.7:1234", "1.2.3.4", "5.6.7.8", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:80", "198.51.100.9", "", "198.51.100.9"},
+		{"skips trusted hops", "10.1.2.3:80", "198.51.100.9, 192.168.1.1, 10.0.0.5", "", "198.51.100.9"},
+		{"spoofed leftmost entry", "10.1.2.3:80", "6.6.6.6, 198.51.100.9", "", "198.51.100.9"},
+		{"invalid entry stops the walk", "10.1.2.3:80", "198.51.100.9, garbage, 10.0.0.5", "", "10.0.0.5"},
+		{"X-Real-IP fallback", "192.168.1.1:80", "", "198.51.100.9", "198.51.100.9"},
+		{"no headers", "10.1.2.3:80", "", "", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/todo", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			req.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := resolveClientIP(req).String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrustedProxies_Invalid(t *testing.T) {
+	if _, err := parseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expected an error for an invalid CIDR")
+	}
+	if _, err := parseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Fatal("expected an error for an invalid address")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// resolvedConfig records the value every getEnv* helper returned, keyed by
+// variable name, for logStartupSummary. Only written during startup.
+var resolvedConfig = map[string]string{}
+
+// recordConfig remembers the resolved value of key
+func recordConfig(key string, value interface{}) {
+	resolvedConfig[key] = fmt.Sprint(value)
+}
+
+// getEnvString reads a string from the environment, returning def when the
+// variable is unset
+func getEnvString(key, def string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		value = def
+	}
+	recordConfig(key, value)
+	return value
+}
+
+// getEnvDuration reads a Go duration (e.g. "15s") from the environment,
+// returning def when the variable is unset. Invalid or non-positive values
+// are fatal so misconfiguration is caught at startup.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		recordConfig(key, def)
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s %q: must be a positive duration such as 15s", key, value)
+	}
+	recordConfig(key, d)
+	return d
+}
+
+// getEnvOptionalDuration reads a Go duration for a feature that is off by
+// default: unset or 0 returns 0, meaning disabled. Invalid or negative values
+// are fatal.
+func getEnvOptionalDuration(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" || value == "0" {
+		recordConfig(key, time.Duration(0))
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Fatalf("Invalid %s %q: must be a duration such as 15s, or 0 to disable", key, value)
+	}
+	recordConfig(key, d)
+	return d
+}
+
+// getEnvInt reads a non-negative integer from the environment, returning def
+// when the variable is unset. Invalid values are fatal.
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		recordConfig(key, def)
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid %s %q: must be a non-negative integer", key, value)
+	}
+	recordConfig(key, n)
+	return n
+}
+
+// getEnvBool reads a boolean from the environment, returning def when the
+// variable is unset. Invalid values are fatal.
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		recordConfig(key, def)
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: must be true or false", key, value)
+	}
+	recordConfig(key, b)
+	return b
+}
+
+// getEnvList reads a comma-separated list from the environment, returning def
+// when the variable is unset
+func getEnvList(key string, def []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		recordConfig(key, def)
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	recordConfig(key, list)
+	return list
+}
+
+// secretConfigWords mark variables whose values logStartupSummary redacts
+var secretConfigWords = []string{"KEY", "SECRET", "PASSWORD", "TOKEN"}
+
+// redactedConfig returns resolvedConfig with secret values replaced by
+// "[redacted]" (or "" when unset, so a missing secret is still visible)
+func redactedConfig() map[string]string {
+	config := make(map[string]string, len(resolvedConfig))
+	for key, value := range resolvedConfig {
+		for _, word := range secretConfigWords {
+			if strings.Contains(key, word) && value != "" {
+				value = "[redacted]"
+				break
+			}
+		}
+		config[key] = value
+	}
+	return config
+}
+
+// routeList returns every route registered on router as "METHODS /path",
+// sorted, with "ANY" for routes that match every method
+func routeList(router *mux.Router) []string {
+	var routes []string
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods := "ANY"
+		if m, err := route.GetMethods(); err == nil {
+			methods = strings.Join(m, ",")
+		}
+		routes = append(routes, methods+" "+path)
+		return nil
+	})
+	sort.Strings(routes)
+	return routes
+}
+
+// logStartupSummary logs the registered routes and the resolved configuration
+// (with secrets redacted) as one structured entry once the router is built
+func logStartupSummary(router *mux.Router) {
+	routes := routeList(router)
+	log.WithFields(log.Fields{
+		"routes": routes,
+		"config": redactedConfig(),
+	}).Infof("Startup summary: %d routes, %d settings", len(routes), len(resolvedConfig))
+}
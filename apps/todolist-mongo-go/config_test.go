@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRedactedConfig(t *testing.T) {
+	prev := resolvedConfig
+	resolvedConfig = map[string]string{}
+	defer func() { resolvedConfig = prev }()
+
+	t.Setenv("API_KEY", "s3cret")
+	t.Setenv("MAX_TAGS_TEST", "7")
+	getEnvString("API_KEY", "")
+	getEnvInt("MAX_TAGS_TEST", 3)
+	getEnvDuration("UNSET_TIMEOUT_TEST", 5*time.Second)
+	getEnvString("UNSET_SECRET_TEST", "")
+
+	want := map[string]string{
+		"API_KEY":            "[redacted]",
+		"MAX_TAGS_TEST":      "7",
+		"UNSET_TIMEOUT_TEST": "5s",
+		"UNSET_SECRET_TEST":  "",
+	}
+	if got := redactedConfig(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if resolvedConfig["API_KEY"] != "s3cret" {
+		t.Fatal("redaction must not modify the recorded value")
+	}
+}
+
+func TestGetEnvOptionalDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{"": 0, "0": 0, "0s": 0, "20s": 20 * time.Second} {
+		t.Setenv("OPTIONAL_DELAY_TEST", value)
+		if got := getEnvOptionalDuration("OPTIONAL_DELAY_TEST"); got != want {
+			t.Errorf("%q: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRouteList(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	router := mux.NewRouter()
+	router.HandleFunc("/todo", noop).Methods("GET", "HEAD")
+	router.HandleFunc("/todo/{id}", noop).Methods("DELETE")
+	router.HandleFunc("/favicon.ico", noop)
+
+	want := []string{"ANY /favicon.ico", "DELETE /todo/{id}", "GET,HEAD /todo"}
+	if got := routeList(router); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/version"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ServerInfo describes the connected deployment and what it supports, for
+// diagnosing features that depend on server capabilities
+type ServerInfo struct {
+	ServerVersion  string `json:"serverVersion"`
+	DriverVersion  string `json:"driverVersion"`
+	WireVersion    int32  `json:"wireVersion"`
+	MaxWireVersion int32  `json:"serverMaxWireVersion"`
+	Topology       string `json:"topology"`
+	ReplicaSet     string `json:"replicaSet,omitempty"`
+	Transactions   bool   `json:"transactions"`
+	ChangeStreams  bool   `json:"changeStreams"`
+	Error          string `json:"error,omitempty"`
+}
+
+// serverInfo is gathered once in main after connecting
+var serverInfo ServerInfo
+
+// changeStreamWireVersion is the first wire version (MongoDB 3.6) with
+// change streams
+const changeStreamWireVersion = 6
+
+// gatherServerInfo asks the server for its version ("buildInfo") and
+// topology ("hello"). The wire version is the one the driver speaks with the
+// server: the server's maximum, capped at the newest the driver supports.
+// Lookup failures are recorded in Error rather than failing startup.
+func gatherServerInfo(client *mongo.Client) ServerInfo {
+	info := ServerInfo{DriverVersion: version.Driver, Topology: "unknown"}
+	admin := client.Database("admin")
+
+	ctx, cancel := opContext()
+	defer cancel()
+
+	var build struct {
+		Version string `bson:"version"`
+	}
+	if err := admin.RunCommand(ctx, bson.M{"buildInfo": 1}).Decode(&build); err != nil {
+		log.Warnf("Could not read server build info: %v", err)
+		info.Error = err.Error()
+	}
+	info.ServerVersion = build.Version
+
+	var hello struct {
+		MaxWireVersion int32  `bson:"maxWireVersion"`
+		SetName        string `bson:"setName"`
+		Msg            string `bson:"msg"`
+	}
+	if err := admin.RunCommand(ctx, bson.M{"hello": 1}).Decode(&hello); err != nil {
+		log.Warnf("Could not read server topology: %v", err)
+		info.Error = err.Error()
+		return info
+	}
+
+	info.MaxWireVersion = hello.MaxWireVersion
+	info.WireVersion = hello.MaxWireVersion
+	if driverMax := topology.SupportedWireVersions.Max; info.WireVersion > driverMax {
+		info.WireVersion = driverMax
+	}
+	switch {
+	case hello.Msg == "isdbgrid":
+		info.Topology = "sharded"
+	case hello.SetName != "":
+		info.Topology = "replicaSet"
+		info.ReplicaSet = hello.SetName
+	default:
+		info.Topology = "standalone"
+	}
+	info.Transactions = info.Topology != "standalone"
+	info.ChangeStreams = info.Topology != "standalone" && info.WireVersion >= changeStreamWireVersion
+	return info
+}
+
+// GetDebugInfo returns the server and driver diagnostics gathered at startup.
+// Debug-only: registered when debugEndpointsEnabled.
+func GetDebugInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serverInfo)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ItemDiff is the result of POST /todo/diff. Added and Changed hold the
+// current items; Removed holds the snapshot's copy of items that are gone.
+type ItemDiff struct {
+	Added   []*TodoItemModel `json:"added"`
+	Removed []*TodoItemModel `json:"removed"`
+	Changed []*TodoItemModel `json:"changed"`
+}
+
+// sameContent reports whether a and b have the same user-visible fields,
+// ignoring timestamps other than the due date
+func sameContent(a, b *TodoItemModel) bool {
+	if a.Description != b.Description || a.Completed != b.Completed ||
+		a.Status != b.Status || a.Priority != b.Priority || a.Owner != b.Owner {
+		return false
+	}
+	if len(a.Tags) != len(b.Tags) {
+		return false
+	}
+	for i := range a.Tags {
+		if a.Tags[i] != b.Tags[i] {
+			return false
+		}
+	}
+	if (a.DueDate == nil) != (b.DueDate == nil) {
+		return false
+	}
+	return a.DueDate == nil || a.DueDate.Equal(*b.DueDate)
+}
+
+// diffItems compares a snapshot with the current items by ID. An item in
+// both counts as changed when its updatedAt moved or its content differs, so
+// edits are caught even when one side has no updatedAt. Each list is sorted
+// by ID.
+func diffItems(snapshot, current []*TodoItemModel) ItemDiff {
+	diff := ItemDiff{Added: []*TodoItemModel{}, Removed: []*TodoItemModel{}, Changed: []*TodoItemModel{}}
+	before := make(map[string]*TodoItemModel, len(snapshot))
+	for _, item := range snapshot {
+		before[item.Id.Hex()] = item
+	}
+
+	for _, item := range current {
+		old, ok := before[item.Id.Hex()]
+		if !ok {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		delete(before, item.Id.Hex())
+		if !old.UpdatedAt.Equal(item.UpdatedAt) || !sameContent(old, item) {
+			diff.Changed = append(diff.Changed, item)
+		}
+	}
+	for _, item := range before {
+		diff.Removed = append(diff.Removed, item)
+	}
+
+	for _, list := range [][]*TodoItemModel{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Id.Hex() < list[j].Id.Hex() })
+	}
+	return diff
+}
+
+// DiffItems compares a JSON array of items previously downloaded from
+// /todo/export.json (or GET /todo) with the live items and returns what was
+// added, removed and changed since. Soft-deleted items count as removed.
+func DiffItems(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	var snapshot []*TodoItemModel
+	if err := decodeJSONStrict(r, &snapshot); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	for _, item := range snapshot {
+		if item == nil || item.Id.IsZero() {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Every snapshot item needs an id")
+			return
+		}
+	}
+
+	current, err := findTodoItems(collection, notDeleted(bson.M{}), options.Find())
+	if err != nil {
+		writeServerError(w, "Failed to retrieve todo items", err)
+		return
+	}
+
+	diff := diffItems(snapshot, current)
+	log.WithFields(log.Fields{
+		"snapshot": len(snapshot),
+		"added":    len(diff.Added),
+		"removed":  len(diff.Removed),
+		"changed":  len(diff.Changed),
+	}).Info("Diff TodoItems against snapshot")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
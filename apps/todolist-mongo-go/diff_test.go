@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffItems(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	kept := &TodoItemModel{Id: primitive.NewObjectID(), Description: "Kept", UpdatedAt: t0}
+	edited := &TodoItemModel{Id: primitive.NewObjectID(), Description: "Old text", UpdatedAt: t0}
+	touched := &TodoItemModel{Id: primitive.NewObjectID(), Description: "Touched", UpdatedAt: t0}
+	removed := &TodoItemModel{Id: primitive.NewObjectID(), Description: "Removed", UpdatedAt: t0}
+	added := &TodoItemModel{Id: primitive.NewObjectID(), Description: "Added", UpdatedAt: t0}
+
+	editedNow := *edited
+	editedNow.Description = "New text"
+	touchedNow := *touched
+	touchedNow.UpdatedAt = t0.Add(time.Minute)
+	keptNow := *kept
+
+	diff := diffItems(
+		[]*TodoItemModel{kept, edited, touched, removed},
+		[]*TodoItemModel{&keptNow, &editedNow, &touchedNow, added},
+	)
+
+	if len(diff.Added) != 1 || diff.Added[0] != added {
+		t.Errorf("added: got %v, want only %q", diff.Added, added.Description)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != removed {
+		t.Errorf("removed: got %v, want only %q", diff.Removed, removed.Description)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatalf("changed: got %d items, want 2", len(diff.Changed))
+	}
+	for _, item := range diff.Changed {
+		if item != &editedNow && item != &touchedNow {
+			t.Errorf("unexpected changed item %q", item.Description)
+		}
+	}
+}
+
+func TestDiffItems_Empty(t *testing.T) {
+	diff := diffItems(nil, nil)
+	if diff.Added == nil || diff.Removed == nil || diff.Changed == nil {
+		t.Fatal("empty diff should have empty arrays, not null")
+	}
+}
+
+func TestDiffItems_RejectsItemWithoutID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/todo/diff", strings.NewReader(`[{"description": "No id"}]`))
+	rec := httptest.NewRecorder()
+	DiffItems(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DuplicateGroup is a normalized description shared by more than one live
+// item. Description is the wording of the oldest of them.
+type DuplicateGroup struct {
+	Key         string               `bson:"_id" json:"key"`
+	Description string               `bson:"description" json:"description"`
+	Count       int64                `bson:"count" json:"count"`
+	IDs         []primitive.ObjectID `bson:"ids" json:"ids"`
+}
+
+// GetDuplicates lists descriptions used by more than one item with the IDs of
+// those items, oldest first. Descriptions are compared by descriptionKey, so
+// "Buy Milk" and "buy  milk" are duplicates; items not yet backfilled by the
+// migration are compared on their exact description.
+func GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	log.Info("Get duplicate TodoItems")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         bson.M{"$ifNull": bson.A{"$descriptionKey", "$description"}},
+			"description": bson.M{"$first": "$description"},
+			"count":       bson.M{"$sum": 1},
+			"ids":         bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate duplicates: %v", err)
+		writeServerError(w, "Failed to retrieve duplicates", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	groups := []DuplicateGroup{}
+	if err := cur.All(ctx, &groups); err != nil {
+		log.Errorf("Failed to decode duplicates: %v", err)
+		writeServerError(w, "Failed to retrieve duplicates", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// MergeRequest folds the duplicates into the primary item
+type MergeRequest struct {
+	Primary    string   `json:"primary"`
+	Duplicates []string `json:"duplicates"`
+}
+
+// mergeError is a client error detected inside the merge transaction
+type mergeError struct {
+	status  int
+	title   string
+	message string
+}
+
+func (e *mergeError) Error() string { return e.message }
+
+// MergeItems adds the tags of the duplicate items to the primary item with
+// $addToSet, soft-deletes the duplicates and returns the merged primary. The
+// steps run in a transaction when the deployment supports one.
+func MergeItems(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	var req MergeRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	primaryID, err := primitive.ObjectIDFromHex(req.Primary)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("invalid primary ID %q", req.Primary))
+		return
+	}
+	if len(req.Duplicates) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "duplicates must list at least one ID")
+		return
+	}
+	if len(req.Duplicates) > maxBulkItems {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("At most %d IDs can be sent at once", maxBulkItems))
+		return
+	}
+	duplicateIDs, err := parseObjectIDs(req.Duplicates)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	for _, id := range duplicateIDs {
+		if id == primaryID {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "primary cannot also be listed as a duplicate")
+			return
+		}
+	}
+
+	log.WithFields(log.Fields{"primary": req.Primary, "duplicates": len(duplicateIDs)}).Info("Merging TodoItems")
+
+	var merged TodoItemModel
+	ctx, cancel := opContext()
+	defer cancel()
+	err = withTransaction(ctx, func(ctx context.Context) error {
+		var primary TodoItemModel
+		if err := collection.FindOne(ctx, notDeleted(bson.M{"_id": primaryID})).Decode(&primary); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return &mergeError{http.StatusNotFound, "Not Found", "Primary todo item not found"}
+			}
+			return err
+		}
+
+		cur, err := collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": duplicateIDs}}))
+		if err != nil {
+			return err
+		}
+		var duplicates []TodoItemModel
+		if err := cur.All(ctx, &duplicates); err != nil {
+			return err
+		}
+		if len(duplicates) != len(duplicateIDs) {
+			return &mergeError{http.StatusNotFound, "Not Found", fmt.Sprintf("%d of %d duplicate items not found", len(duplicateIDs)-len(duplicates), len(duplicateIDs))}
+		}
+
+		tags := append([]string{}, primary.Tags...)
+		seen := map[string]bool{}
+		for _, tag := range tags {
+			seen[tag] = true
+		}
+		var added []string
+		for _, duplicate := range duplicates {
+			for _, tag := range duplicate.Tags {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+					added = append(added, tag)
+				}
+			}
+		}
+		if err := validateTags(tags); err != nil {
+			return &mergeError{http.StatusBadRequest, "Bad Request", err.Error()}
+		}
+
+		now := time.Now().UTC()
+		update := bumpVersion(bson.M{"$set": bson.M{"updatedAt": now}})
+		if len(added) > 0 {
+			update["$addToSet"] = bson.M{"tags": bson.M{"$each": added}}
+		}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		// the primary may have been deleted since it was read; don't
+		// resurrect it with the merged tags
+		if err := collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": primaryID}), update, opts).Decode(&merged); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return &mergeError{http.StatusNotFound, "Not Found", "Primary todo item not found"}
+			}
+			return err
+		}
+
+		_, err = collection.UpdateMany(ctx,
+			notDeleted(bson.M{"_id": bson.M{"$in": duplicateIDs}}),
+			bumpVersion(bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}}))
+		return err
+	})
+	if err != nil {
+		var clientErr *mergeError
+		if errors.As(err, &clientErr) {
+			writeErrorResponse(w, clientErr.status, clientErr.title, clientErr.message)
+			return
+		}
+		log.Errorf("Failed to merge todo items: %v", err)
+		writeServerError(w, "Failed to merge todo items", err)
+		return
+	}
+	recordAudit(r, collection, auditUpdate, primaryID)
+	recordAudit(r, collection, auditDelete, duplicateIDs...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merged)
+}
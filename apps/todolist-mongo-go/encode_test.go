@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// discardWriter is a ResponseWriter that drops the body
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+func benchmarkItems(n int) []*TodoItemModel {
+	now := time.Now().UTC()
+	items := make([]*TodoItemModel, n)
+	for i := range items {
+		items[i] = &TodoItemModel{
+			Id:          primitive.NewObjectID(),
+			Description: fmt.Sprintf("todo item number %d", i),
+			Priority:    "medium",
+			Tags:        []string{"home", "errands"},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+	return items
+}
+
+func benchmarkWriteJSON(b *testing.B, pooled bool) {
+	prev := pooledJSON
+	pooledJSON = pooled
+	defer func() { pooledJSON = prev }()
+
+	items := benchmarkItems(50)
+	w := &discardWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		writeJSON(w, items)
+	}
+}
+
+func BenchmarkWriteJSON_Unpooled(b *testing.B) { benchmarkWriteJSON(b, false) }
+func BenchmarkWriteJSON_Pooled(b *testing.B)   { benchmarkWriteJSON(b, true) }
+
+func TestWriteJSON_PooledMatchesUnpooled(t *testing.T) {
+	prev := pooledJSON
+	defer func() { pooledJSON = prev }()
+
+	items := benchmarkItems(3)
+	var bodies [2]string
+	for i, pooled := range []bool{false, true} {
+		pooledJSON = pooled
+		rec := httptest.NewRecorder()
+		if err := writeJSON(rec, items); err != nil {
+			t.Fatalf("pooled=%v: %v", pooled, err)
+		}
+		bodies[i] = rec.Body.String()
+	}
+	if bodies[0] != bodies[1] {
+		t.Fatalf("pooled output differs\nunpooled: %s\n  pooled: %s", bodies[0], bodies[1])
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// formatTime renders t as RFC3339, or "" for the zero value
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// openExport runs the query for an export of the items matching the
+// buildFilter params, writing an error response and returning ok=false when
+// it fails. The query is bounded by MONGODB_OP_TIMEOUT; streaming the results
+// lasts as long as the client keeps reading.
+func openExport(w http.ResponseWriter, r *http.Request) (cur *mongo.Cursor, ok bool) {
+	collection := todoCollection(r)
+	filter, err := buildFilter(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return nil, false
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"_id": 1})
+	ctx, cancel := opContext()
+	cur, err = collection.Find(ctx, filter, findOptions)
+	cancel()
+	if err != nil {
+		log.Errorf("Failed to query todo items: %v", err)
+		writeServerError(w, "Failed to export todo items", err)
+		return nil, false
+	}
+	return cur, true
+}
+
+// itemCursor is the part of *mongo.Cursor an export reads
+type itemCursor interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	Err() error
+}
+
+// streamItems decodes each document of cur and passes it to write. An
+// undecodable document is logged and skipped, unless STRICT_DECODE is set, in
+// which case it ends the stream with an error like a cursor or write error.
+func streamItems(r *http.Request, cur itemCursor, write func(*TodoItemModel) error) error {
+	for cur.Next(r.Context()) {
+		var item TodoItemModel
+		if err := cur.Decode(&item); err != nil {
+			if strictDecode {
+				return fmt.Errorf("decode todo item: %w", err)
+			}
+			log.Warnf("Skipping undecodable todo item: %v", err)
+			continue
+		}
+		if err := write(&item); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// abortStream ends a streamed response that failed after its 200 status and
+// part of its body were sent, when the status can no longer report the error.
+// The error is logged and the body is left unterminated (a JSON array without
+// its closing bracket, a CSV without its final rows), then the connection is
+// aborted so the client sees a transfer error rather than a complete but
+// truncated download.
+func abortStream(r *http.Request, err error) {
+	log.Errorf("Aborting export after a mid-stream error: %v", err)
+	panic(http.ErrAbortHandler)
+}
+
+// ExportCSV streams the items matching the buildFilter params as a CSV
+// download. A failure part way through aborts the response (see abortStream).
+func ExportCSV(w http.ResponseWriter, r *http.Request) {
+	log.Info("Export TodoItems as CSV")
+	cur, ok := openExport(w, r)
+	if !ok {
+		return
+	}
+	defer cur.Close(r.Context())
+	writeCSVExport(w, r, cur)
+}
+
+// writeCSVExport writes the CSV export of cur to w
+func writeCSVExport(w http.ResponseWriter, r *http.Request, cur itemCursor) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.csv"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "description", "completed", "priority", "tags", "dueDate", "createdAt", "updatedAt"})
+	err := streamItems(r, cur, func(item *TodoItemModel) error {
+		dueDate := ""
+		if item.DueDate != nil {
+			dueDate = formatTime(*item.DueDate)
+		}
+		return writer.Write([]string{
+			item.Id.Hex(),
+			item.Description,
+			strconv.FormatBool(item.Completed),
+			item.Priority,
+			strings.Join(item.Tags, ","),
+			dueDate,
+			formatTime(item.CreatedAt),
+			formatTime(item.UpdatedAt),
+		})
+	})
+	if err != nil {
+		abortStream(r, err)
+	}
+	writer.Flush()
+}
+
+// ExportJSON streams the items matching the buildFilter params as a JSON
+// array download, one item at a time rather than building the whole list. A
+// failure part way through aborts the response (see abortStream).
+func ExportJSON(w http.ResponseWriter, r *http.Request) {
+	log.Info("Export TodoItems as JSON")
+	cur, ok := openExport(w, r)
+	if !ok {
+		return
+	}
+	defer cur.Close(r.Context())
+	writeJSONExport(w, r, cur)
+}
+
+// writeJSONExport writes the JSON array export of cur to w
+func writeJSONExport(w http.ResponseWriter, r *http.Request, cur itemCursor) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.json"`)
+
+	io.WriteString(w, "[")
+	first := true
+	err := streamItems(r, cur, func(item *TodoItemModel) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			log.Warnf("Skipping unencodable todo item %s: %v", item.Id.Hex(), err)
+			return nil
+		}
+		if !first {
+			io.WriteString(w, ",")
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		abortStream(r, err)
+	}
+	io.WriteString(w, "]\n")
+}
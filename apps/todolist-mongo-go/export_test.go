@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeCursor serves docs like a *mongo.Cursor
+type fakeCursor struct {
+	docs []bson.M
+	pos  int
+}
+
+func (c *fakeCursor) Next(ctx context.Context) bool {
+	c.pos++
+	return c.pos <= len(c.docs)
+}
+
+func (c *fakeCursor) Decode(val interface{}) error {
+	data, err := bson.Marshal(c.docs[c.pos-1])
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, val)
+}
+
+func (c *fakeCursor) Err() error { return nil }
+
+// exportDocs has an undecodable document (a numeric description) between two
+// good ones; the first is large enough that the 200 and part of the body are
+// sent before the bad document is reached
+func exportDocs() []bson.M {
+	return []bson.M{
+		{"description": strings.Repeat("x", 16<<10)},
+		{"description": 42},
+		{"description": "last"},
+	}
+}
+
+func TestStreamItems_SkipsUndecodable(t *testing.T) {
+	defer func(strict bool) { strictDecode = strict }(strictDecode)
+	req := httptest.NewRequest(http.MethodGet, "/todo/export.json", nil)
+	collect := func() (int, error) {
+		n := 0
+		err := streamItems(req, &fakeCursor{docs: exportDocs()}, func(*TodoItemModel) error {
+			n++
+			return nil
+		})
+		return n, err
+	}
+
+	strictDecode = false
+	if n, err := collect(); err != nil || n != 2 {
+		t.Fatalf("expected the bad document to be skipped, got %d items, %v", n, err)
+	}
+	strictDecode = true
+	if n, err := collect(); err == nil || n != 1 {
+		t.Fatalf("expected an error after 1 item with STRICT_DECODE, got %d items, %v", n, err)
+	}
+}
+
+func TestWriteJSONExport_AbortsOnMidStreamError(t *testing.T) {
+	defer func(strict bool) { strictDecode = strict }(strictDecode)
+	strictDecode = true
+
+	server := httptest.NewServer(panicRecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONExport(w, r, &fakeCursor{docs: exportDocs()})
+	})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the 200 to have been sent already, got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("expected the transfer to fail, got a complete body of %d bytes", len(body))
+	}
+	if !strings.HasPrefix(string(body), `[{"id"`) || strings.HasSuffix(string(body), "]\n") {
+		t.Fatalf("expected an unterminated array, got %.40q...", body)
+	}
+}
+
+func TestWriteJSONExport(t *testing.T) {
+	docs := []bson.M{{"description": "a"}, {"description": "b"}}
+	rec := httptest.NewRecorder()
+	writeJSONExport(rec, httptest.NewRequest(http.MethodGet, "/todo/export.json", nil), &fakeCursor{docs: docs})
+
+	var items []TodoItemModel
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("expected a complete JSON array: %v", err)
+	}
+	if len(items) != 2 || items[1].Description != "b" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// FieldInfo describes one TodoItemModel field for clients that build forms
+// dynamically
+type FieldInfo struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Required      bool     `json:"required"`
+	Editable      bool     `json:"editable"`
+	AllowedValues []string `json:"allowedValues,omitempty"`
+	MaxItems      int      `json:"maxItems,omitempty"`
+	MaxLength     int      `json:"maxLength,omitempty"`
+	MaxBytes      int      `json:"maxBytes,omitempty"`
+	Description   string   `json:"description"`
+}
+
+// todoFields describes the item JSON. It is maintained by hand next to
+// TodoItemModel; keep the two in step when fields are added.
+func todoFields() []FieldInfo {
+	priorities := make([]string, 0, len(validPriorities))
+	for priority := range validPriorities {
+		priorities = append(priorities, priority)
+	}
+	sort.Strings(priorities)
+
+	fields := []FieldInfo{
+		{Name: "id", Type: "string", Description: "Hex ObjectID assigned on create"},
+		{Name: "description", Type: "string", Required: true, MaxLength: maxDescriptionLength, MaxBytes: maxDescriptionBytes, Description: "What needs doing; must contain visible characters. maxLength counts characters (runes), maxBytes the UTF-8 size"},
+		{Name: "completed", Type: "boolean", Description: "Whether the item is done; true exactly when status is done"},
+		{Name: "status", Type: "string", AllowedValues: statusNames(), Description: "Workflow state, changed with POST /todo/{id}/status; missing on items created before it existed"},
+		{Name: "priority", Type: "string", AllowedValues: priorities, Description: "Optional priority"},
+		{Name: "tags", Type: "array<string>", MaxItems: maxTags, MaxLength: maxTagLength, Description: "Optional labels; maxLength applies to each tag"},
+		{Name: "dueDate", Type: "datetime", Description: "Optional RFC3339 due date"},
+		{Name: "owner", Type: "string", Description: "User ID from the " + userIDHeader + " header on create"},
+		{Name: "createdAt", Type: "datetime", Description: "Set on create"},
+		{Name: "updatedAt", Type: "datetime", Description: "Set on every change"},
+		{Name: "version", Type: "integer", Description: "Incremented on every change (absent until the first) and sent as GetItem's ETag; DELETE with If-Match or expected_version only deletes at that version"},
+		{Name: "completedAt", Type: "datetime", Description: "Set when the item is completed, cleared when reopened"},
+		{Name: "deletedAt", Type: "datetime", Description: "Set when the item is deleted"},
+		{Name: "deleted", Type: "boolean", Description: "true on soft-deleted items, which are only returned with include_deleted=true or by the sync endpoints; never stored"},
+	}
+	for i := range fields {
+		fields[i].Editable = patchableFields[fields[i].Name]
+	}
+	return fields
+}
+
+// GetFields returns the field metadata from todoFields
+func GetFields(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(todoFields())
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTodoFields_MatchesModel keeps the hand-maintained field list in step
+// with the JSON keys of TodoItemModel
+func TestTodoFields_MatchesModel(t *testing.T) {
+	described := map[string]bool{}
+	for _, field := range todoFields() {
+		described[field.Name] = true
+	}
+
+	modelType := reflect.TypeOf(TodoItemModel{})
+	for i := 0; i < modelType.NumField(); i++ {
+		name := strings.Split(modelType.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if !described[name] {
+			t.Errorf("field %q is missing from todoFields", name)
+		}
+		delete(described, name)
+	}
+	for name := range described {
+		t.Errorf("todoFields describes %q, which is not a TodoItemModel field", name)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// buildFilter turns the list query parameters shared by every list endpoint
+// into a Mongo filter. Soft-deleted items are always excluded. The returned
+// error is meant to be sent back to the client as a 400.
+//
+// Supported parameters:
+//
+//	completed=all|true|false         completion state (default all)
+//	status=a,b                        any of the statuses (see validStatuses)
+//	priority=low|medium|high
+//	tag=a,b (or repeated)             items carrying the tags
+//	tag_mode=any|all                  any of the tags ($in, default) or all ($all)
+//	due_after, due_before             RFC3339, bounds on dueDate
+//	created_after, created_before     RFC3339, bounds on createdAt
+//	has_due_date=true|false           items with or without a dueDate
+//	has_tags=true|false               items with or without any tags
+//	q=text                            case-insensitive description substring
+//	regex=true                        treat q as a (guarded) regular expression
+func buildFilter(r *http.Request) (bson.M, error) {
+	query := r.URL.Query()
+	filter := notDeleted(bson.M{})
+
+	if value := query.Get("completed"); value != "" && value != "all" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid completed value %q: must be all, true or false", value)
+		}
+		filter["completed"] = completed
+	}
+
+	if value := query.Get("status"); value != "" {
+		condition, err := parseStatusFilter(value)
+		if err != nil {
+			return nil, err
+		}
+		// Kept under $and because the condition may use $or, like q does
+		filter["$and"] = bson.A{condition}
+	}
+
+	if value := query.Get("priority"); value != "" {
+		if !validPriorities[value] {
+			return nil, fmt.Errorf("invalid priority %q: must be one of low, medium, high", value)
+		}
+		filter["priority"] = value
+	}
+
+	var tags []string
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	switch mode := query.Get("tag_mode"); mode {
+	case "", "any":
+		if len(tags) > 0 {
+			filter["tags"] = bson.M{"$in": tags}
+		}
+	case "all":
+		if len(tags) > 0 {
+			filter["tags"] = bson.M{"$all": tags}
+		}
+	default:
+		return nil, fmt.Errorf("invalid tag_mode %q: must be any or all", mode)
+	}
+
+	if err := addTimeRange(filter, query.Get("due_after"), query.Get("due_before"), "dueDate", "due"); err != nil {
+		return nil, err
+	}
+	if err := addTimeRange(filter, query.Get("created_after"), query.Get("created_before"), "createdAt", "created"); err != nil {
+		return nil, err
+	}
+
+	// Presence conditions go under $and so they combine with the tag and
+	// due date conditions on the same fields
+	presence := []struct {
+		param       string
+		with, empty bson.M
+	}{
+		{"has_due_date", bson.M{"dueDate": bson.M{"$type": "date"}}, bson.M{"dueDate": nil}},
+		{"has_tags", bson.M{"tags.0": bson.M{"$exists": true}}, bson.M{"$or": bson.A{
+			bson.M{"tags": bson.M{"$exists": false}},
+			bson.M{"tags": bson.M{"$size": 0}},
+		}}},
+	}
+	for _, p := range presence {
+		value := query.Get(p.param)
+		if value == "" {
+			continue
+		}
+		has, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: must be true or false", p.param, value)
+		}
+		condition := p.empty
+		if has {
+			condition = p.with
+		}
+		and, _ := filter["$and"].(bson.A)
+		filter["$and"] = append(and, condition)
+	}
+
+	if q := query.Get("q"); q != "" {
+		raw := false
+		if value := query.Get("regex"); value != "" {
+			var err error
+			if raw, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("invalid regex value %q: must be true or false", value)
+			}
+		}
+		condition, err := searchCondition(q, raw)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range condition {
+			filter[key] = value
+		}
+	}
+
+	return filter, nil
+}
+
+// searchCondition matches descriptions containing q, case-insensitively, or
+// matching q as a guarded regular expression when raw is set
+func searchCondition(q string, raw bool) (bson.M, error) {
+	pattern, err := searchPattern(q, raw)
+	if err != nil {
+		return nil, err
+	}
+	if raw {
+		return bson.M{"description": primitive.Regex{Pattern: pattern, Options: "i"}}, nil
+	}
+	// Literal searches match the normalized key, so extra or differently
+	// cased whitespace in q or the item doesn't matter
+	return bson.M{"$or": bson.A{
+		bson.M{"descriptionKey": primitive.Regex{Pattern: regexp.QuoteMeta(descriptionKey(q))}},
+		bson.M{"descriptionKey": bson.M{"$exists": false}, "description": primitive.Regex{Pattern: pattern, Options: "i"}},
+	}}, nil
+}
+
+// addTimeRange adds $gte/$lte bounds on field from optional RFC3339 params
+func addTimeRange(filter bson.M, after, before, field, param string) error {
+	if after == "" && before == "" {
+		return nil
+	}
+	bounds := bson.M{}
+	var start, end time.Time
+	var err error
+	if after != "" {
+		if start, err = time.Parse(time.RFC3339, after); err != nil {
+			return fmt.Errorf("invalid %s_after value %q: must be an RFC3339 timestamp", param, after)
+		}
+		bounds["$gte"] = start
+	}
+	if before != "" {
+		if end, err = time.Parse(time.RFC3339, before); err != nil {
+			return fmt.Errorf("invalid %s_before value %q: must be an RFC3339 timestamp", param, before)
+		}
+		bounds["$lte"] = end
+	}
+	if after != "" && before != "" && end.Before(start) {
+		return fmt.Errorf("%s_before must not be earlier than %s_after", param, param)
+	}
+	filter[field] = bounds
+	return nil
+}
+
+// Pagination bounds for endpoints that accept limit and offset
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// parsePagination reads the optional limit (1..maxPageLimit, default
+// defaultPageLimit) and offset (>= 0) query parameters
+func parsePagination(r *http.Request) (limit, offset int64, err error) {
+	query := r.URL.Query()
+	limit = defaultPageLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("invalid limit %q: must be between 1 and %d", value, maxPageLimit)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q: must be a non-negative integer", value)
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildFilter_TagMode(t *testing.T) {
+	tests := []struct {
+		query string
+		want  interface{}
+	}{
+		{"/todo?tag=work,urgent", bson.M{"$in": []string{"work", "urgent"}}},
+		{"/todo?tag=work&tag=urgent&tag_mode=any", bson.M{"$in": []string{"work", "urgent"}}},
+		{"/todo?tag=work,urgent&tag_mode=all", bson.M{"$all": []string{"work", "urgent"}}},
+		{"/todo?tag_mode=all", nil},
+	}
+	for _, tt := range tests {
+		filter, err := buildFilter(httptest.NewRequest("GET", tt.query, nil))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.query, err)
+		}
+		got, ok := filter["tags"]
+		if tt.want == nil {
+			if ok {
+				t.Fatalf("%s: expected no tags condition, got %v", tt.query, got)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFilter_SearchUsesDescriptionKey(t *testing.T) {
+	filter, err := buildFilter(httptest.NewRequest("GET", "/todo?q=Buy%20%20Milk", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	or, ok := filter["$or"].(bson.A)
+	if !ok || len(or) != 2 {
+		t.Fatalf("expected a two-branch $or, got %v", filter)
+	}
+	want := bson.M{"descriptionKey": primitive.Regex{Pattern: "buy milk"}}
+	if !reflect.DeepEqual(or[0], want) {
+		t.Fatalf("got %v, want %v", or[0], want)
+	}
+
+	filter, err = buildFilter(httptest.NewRequest("GET", "/todo?q=^buy&regex=true", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := filter["$or"]; ok {
+		t.Fatalf("raw regex searches should match description, got %v", filter)
+	}
+	if _, ok := filter["description"].(primitive.Regex); !ok {
+		t.Fatalf("expected a description regex, got %v", filter)
+	}
+}
+
+func TestBuildFilter_Completed(t *testing.T) {
+	tests := map[string]interface{}{
+		"/todo":                 nil,
+		"/todo?completed=all":   nil,
+		"/todo?completed=true":  true,
+		"/todo?completed=false": false,
+	}
+	for query, want := range tests {
+		filter, err := buildFilter(httptest.NewRequest("GET", query, nil))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", query, err)
+		}
+		got, ok := filter["completed"]
+		if want == nil {
+			if ok {
+				t.Errorf("%s: expected no completed condition, got %v", query, got)
+			}
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestBuildFilter_Presence(t *testing.T) {
+	filter, err := buildFilter(httptest.NewRequest("GET", "/todo?status=done&has_due_date=true&has_tags=false&tag=work", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	and, ok := filter["$and"].(bson.A)
+	if !ok || len(and) != 3 {
+		t.Fatalf("expected the status and both presence conditions under $and, got %v", filter)
+	}
+	if want := (bson.M{"dueDate": bson.M{"$type": "date"}}); !reflect.DeepEqual(and[1], want) {
+		t.Fatalf("got %v, want %v", and[1], want)
+	}
+	if _, ok := and[2].(bson.M)["$or"]; !ok {
+		t.Fatalf("expected has_tags=false to match missing or empty tags, got %v", and[2])
+	}
+	if _, ok := filter["tags"]; !ok {
+		t.Fatalf("the tag condition should be kept, got %v", filter)
+	}
+
+	filter, err = buildFilter(httptest.NewRequest("GET", "/todo?has_due_date=false&has_tags=true", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := bson.A{bson.M{"dueDate": nil}, bson.M{"tags.0": bson.M{"$exists": true}}}
+	if !reflect.DeepEqual(filter["$and"], want) {
+		t.Fatalf("got %v, want %v", filter["$and"], want)
+	}
+}
+
+func TestWithCompletedParam(t *testing.T) {
+	r := httptest.NewRequest("GET", "/todo-completed?completed=false&tag=work", nil)
+	got := withCompletedParam(r, "true")
+	if v := got.URL.Query().Get("completed"); v != "true" {
+		t.Fatalf("completed = %q, want true", v)
+	}
+	if v := got.URL.Query().Get("tag"); v != "work" {
+		t.Fatalf("other params should be kept, tag = %q", v)
+	}
+	if v := r.URL.Query().Get("completed"); v != "false" {
+		t.Fatalf("the original request should not change, completed = %q", v)
+	}
+}
+
+func TestBuildFilter_InvalidParams(t *testing.T) {
+	for _, query := range []string{
+		"/todo?tag=work&tag_mode=some",
+		"/todo?completed=maybe",
+		"/todo?priority=urgent",
+		"/todo?due_after=tomorrow",
+		"/todo?created_after=2024-02-01T00:00:00Z&created_before=2024-01-01T00:00:00Z",
+		"/todo?q=milk&regex=maybe",
+		"/todo?has_due_date=yes",
+		"/todo?has_tags=1x",
+		"/todo?q=(a%2B)%2B%24&regex=true",
+	} {
+		if _, err := buildFilter(httptest.NewRequest("GET", query, nil)); err == nil {
+			t.Fatalf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	limit, offset, err := parsePagination(httptest.NewRequest("GET", "/todo/untagged", nil))
+	if err != nil || limit != defaultPageLimit || offset != 0 {
+		t.Fatalf("defaults: got limit=%d offset=%d err=%v", limit, offset, err)
+	}
+	limit, offset, err = parsePagination(httptest.NewRequest("GET", "/todo/untagged?limit=10&offset=20", nil))
+	if err != nil || limit != 10 || offset != 20 {
+		t.Fatalf("got limit=%d offset=%d err=%v", limit, offset, err)
+	}
+	for _, query := range []string{"?limit=0", "?limit=501", "?limit=x", "?offset=-1"} {
+		if _, _, err := parsePagination(httptest.NewRequest("GET", "/todo/untagged"+query, nil)); err == nil {
+			t.Fatalf("%s: expected an error", query)
+		}
+	}
+}
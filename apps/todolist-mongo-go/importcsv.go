@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// csvImportBatchSize is how many rows each InsertMany of a CSV import carries
+const csvImportBatchSize = 50
+
+// CSVRowError is a problem with one row of an imported CSV; Line is the line
+// of the file the row starts on, counting the header as line 1
+type CSVRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// CSVImportResult reports the outcome of POST /todo/import.csv
+type CSVImportResult struct {
+	Imported int           `json:"imported"`
+	Errors   []CSVRowError `json:"errors"`
+}
+
+// csvRow is a parsed, validated row of an imported CSV
+type csvRow struct {
+	Line int
+	Item NewTodoItem
+}
+
+// parseCSVItem builds the item in record from the columns named in header.
+// completed is a boolean and empty means false; tags are comma-separated
+// within their cell as ExportCSV writes them; dueDate is optional RFC3339.
+func parseCSVItem(header map[string]int, record []string) (NewTodoItem, error) {
+	cell := func(name string) string {
+		if i, ok := header[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	item := NewTodoItem{Description: cell("description"), Priority: cell("priority")}
+	if value := cell("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return item, fmt.Errorf("invalid completed value %q: must be true or false", value)
+		}
+		item.Completed = completed
+	}
+	for _, tag := range strings.Split(cell("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+	if value := cell("dueDate"); value != "" {
+		due, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return item, fmt.Errorf("invalid dueDate value %q: must be an RFC3339 time", value)
+		}
+		item.DueDate = &due
+	}
+	return item, item.validate()
+}
+
+// readImportCSV parses a CSV with a header row naming its columns. Only
+// description is required; id, createdAt, updatedAt and any other columns
+// are ignored, so a file from GET /todo/export.csv can be imported as is.
+// Rows that cannot be used are returned as row errors; a malformed file, too
+// many rows or a body over maxImportBytes is an error.
+func readImportCSV(body io.Reader) ([]csvRow, []CSVRowError, error) {
+	reader := csv.NewReader(body)
+	names, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the CSV is empty; a header row is required")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	header := map[string]int{}
+	for i, name := range names {
+		header[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := header["description"]; !ok {
+		return nil, nil, errors.New("the CSV header must have a description column")
+	}
+
+	var rows []csvRow
+	var rowErrs []CSVRowError
+	for count := 0; ; count++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if count == maxImportItems {
+			return nil, nil, errTooManyItems
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+				rowErrs = append(rowErrs, CSVRowError{Line: parseErr.StartLine, Message: fmt.Sprintf("expected %d columns, got %d", len(names), len(record))})
+				continue
+			}
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		item, err := parseCSVItem(header, record)
+		if err != nil {
+			rowErrs = append(rowErrs, CSVRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, csvRow{Line: line, Item: item})
+	}
+	return rows, rowErrs, nil
+}
+
+// ImportCSV creates items from an uploaded CSV, sent either as the request
+// body or as the "file" field of a multipart form. Each row is validated like
+// POST /todo and the valid rows are inserted with InsertMany in batches of
+// csvImportBatchSize. The response lists the problem rows by line number.
+// With strict=true any problem row aborts the import with 400 before
+// anything is written; by default the valid rows are imported and the others
+// skipped. The body and row count are bounded by MAX_IMPORT_BYTES and
+// MAX_IMPORT_ITEMS like POST /todo/bulk. A duplicate under UNIQUE_DESCRIPTIONS
+// is reported as a row error and skipped by default; with strict=true it
+// aborts the import with 409. Batches are not transactional, so an abort part
+// way through leaves the earlier rows in place; the error says how many were
+// imported.
+func ImportCSV(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	strict := false
+	if value := r.URL.Query().Get("strict"); value != "" {
+		var err error
+		if strict, err = strconv.ParseBool(value); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid strict value. Must be true or false")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	body := io.Reader(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", fmt.Sprintf("Request body exceeds %d bytes", maxImportBytes))
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Multipart uploads must carry the CSV in a file field")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, rowErrs, err := readImportCSV(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", fmt.Sprintf("Request body exceeds %d bytes", maxImportBytes))
+		case err == errTooManyItems:
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("At most %d rows can be imported at once", maxImportItems))
+		default:
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		}
+		return
+	}
+	if rowErrs == nil {
+		rowErrs = []CSVRowError{}
+	}
+	if strict && len(rowErrs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(CSVImportResult{Imported: 0, Errors: rowErrs})
+		return
+	}
+
+	owner := ownerFromRequest(r)
+	if !checkOwnerQuota(w, collection, owner, len(rows)) {
+		return
+	}
+
+	log.WithFields(log.Fields{"rows": len(rows), "rejected": len(rowErrs), "strict": strict}).Info("Importing TodoItems from CSV")
+
+	now := time.Now().UTC()
+	imported := 0
+	for start := 0; start < len(rows); start += csvImportBatchSize {
+		end := start + csvImportBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		docs := make([]interface{}, 0, end-start)
+		for _, row := range rows[start:end] {
+			docs = append(docs, row.Item.toModel(owner, now))
+		}
+
+		// Unless strict, the insert is unordered so a duplicate only skips
+		// its own row
+		ctx, cancel := opContext()
+		result, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(strict))
+		cancel()
+		// InsertedIDs lists every document sent, inserted or not
+		auditInserted := func(n int, failed map[int]bool) {
+			ids := make([]primitive.ObjectID, 0, n)
+			for i, id := range result.InsertedIDs[:n] {
+				if !failed[i] {
+					ids = append(ids, id.(primitive.ObjectID))
+				}
+			}
+			recordAudit(r, collection, auditCreate, ids...)
+		}
+		if failed, ok := duplicateRows(err); ok && !strict {
+			for index := range failed {
+				rowErrs = append(rowErrs, CSVRowError{Line: rows[start+index].Line, Message: "a todo item with this description already exists"})
+			}
+			imported += len(docs) - len(failed)
+			auditInserted(len(docs), failed)
+			continue
+		}
+		if isDuplicateDescription(err) {
+			// the insert is ordered, so the rows before the failing one are in
+			var bulkErr mongo.BulkWriteException
+			if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+				failed := start + bulkErr.WriteErrors[0].Index
+				imported += failed - start
+				auditInserted(failed-start, nil)
+				writeErrorResponse(w, http.StatusConflict, "Conflict", fmt.Sprintf("Line %d: a todo item with this description already exists; %d rows were imported before it", rows[failed].Line, imported))
+				return
+			}
+			writeErrorResponse(w, http.StatusConflict, "Conflict", "A todo item with one of these descriptions already exists")
+			return
+		}
+		if err != nil {
+			log.Errorf("Failed to import todo items after %d rows: %v", imported, err)
+			writeServerError(w, "Failed to import todo items", err)
+			return
+		}
+		imported += len(docs)
+		auditInserted(len(docs), nil)
+	}
+	sort.Slice(rowErrs, func(i, j int) bool { return rowErrs[i].Line < rowErrs[j].Line })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CSVImportResult{Imported: imported, Errors: rowErrs})
+}
+
+// duplicateRows returns the batch indexes an unordered InsertMany rejected,
+// when every failure was a duplicate description
+func duplicateRows(err error) (map[int]bool, bool) {
+	var bulkErr mongo.BulkWriteException
+	if !uniqueDescriptions || !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil, false
+	}
+	failed := map[int]bool{}
+	for _, writeErr := range bulkErr.WriteErrors {
+		// 11000 is MongoDB's duplicate key error
+		if writeErr.Code != 11000 {
+			return nil, false
+		}
+		failed[writeErr.Index] = true
+	}
+	return failed, true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	body := "description,completed,priority,tags\n" +
+		"buy milk,false,high,\"home, shop\"\n" +
+		"\"multi\nline\",true,,\n" +
+		"   ,false,,\n" +
+		"bad priority,false,urgent,\n" +
+		"too,few\n" +
+		"bad completed,maybe,,\n" +
+		"last,,low,work\n"
+	rows, rowErrs, err := readImportCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 valid rows, got %+v", rows)
+	}
+	if rows[0].Line != 2 || rows[0].Item.Priority != "high" || len(rows[0].Item.Tags) != 2 || rows[0].Item.Tags[1] != "shop" {
+		t.Errorf("unexpected first row: %+v", rows[0])
+	}
+	if rows[1].Line != 3 || !rows[1].Item.Completed || rows[1].Item.Description != "multi\nline" {
+		t.Errorf("unexpected second row: %+v", rows[1])
+	}
+	if rows[2].Line != 9 || rows[2].Item.Completed || rows[2].Item.Tags[0] != "work" {
+		t.Errorf("unexpected last row: %+v", rows[2])
+	}
+
+	var lines []int
+	for _, rowErr := range rowErrs {
+		lines = append(lines, rowErr.Line)
+	}
+	if want := []int{5, 6, 7, 8}; len(lines) != len(want) || lines[0] != 5 || lines[1] != 6 || lines[2] != 7 || lines[3] != 8 {
+		t.Fatalf("expected errors on lines %v, got %+v", want, rowErrs)
+	}
+}
+
+func TestReadImportCSV_Invalid(t *testing.T) {
+	prev := maxImportItems
+	defer func() { maxImportItems = prev }()
+	maxImportItems = 2
+
+	for _, body := range []string{
+		"",
+		"completed,priority\nfalse,low\n",
+		"description\n\"unterminated\n",
+		"description\na\nb\nc\n",
+	} {
+		if _, _, err := readImportCSV(strings.NewReader(body)); err == nil {
+			t.Errorf("%q: expected an error", body)
+		}
+	}
+}
+
+func TestImportCSV_StrictRejectsBadRows(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := "description,priority\nok,low\nbad,urgent\n"
+	ImportCSV(rec, httptest.NewRequest(http.MethodPost, "/todo/import.csv?strict=true", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result CSVImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Imported != 0 || len(result.Errors) != 1 || result.Errors[0].Line != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestImportCSV_TooLarge(t *testing.T) {
+	prev := maxImportBytes
+	defer func() { maxImportBytes = prev }()
+	maxImportBytes = 64
+
+	rec := httptest.NewRecorder()
+	body := "description\n" + strings.Repeat("x", 100) + "\n"
+	ImportCSV(rec, httptest.NewRequest(http.MethodPost, "/todo/import.csv", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	collection := setupTestCollection(t)
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", "todos.csv")
+	if err != nil {
+		t.Fatalf("form: %v", err)
+	}
+	part.Write([]byte("description,completed,tags\nfirst,false,home\nsecond,true,\n,false,\n"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/todo/import.csv", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	ImportCSV(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result CSVImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Imported != 2 || len(result.Errors) != 1 || result.Errors[0].Line != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"completed": true, "completedAt": bson.M{"$type": "date"}})
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 completed item with completedAt, got %d (%v)", count, err)
+	}
+}
+
+func TestImportCSV_DuplicatesSkipped(t *testing.T) {
+	collection := setupTestCollection(t)
+	prev := uniqueDescriptions
+	uniqueDescriptions = true
+	defer func() { uniqueDescriptions = prev }()
+	ensureIndexes(collection, false)
+
+	if _, err := collection.InsertOne(context.TODO(), bson.M{"description": "existing", "descriptionKey": descriptionKey("existing"), "completed": false}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/todo/import.csv", strings.NewReader("description\nfirst\nexisting\n,\nlast\n"))
+	rec := httptest.NewRecorder()
+	ImportCSV(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result CSVImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Imported != 2 || len(result.Errors) != 2 || result.Errors[0].Line != 3 || result.Errors[1].Line != 4 {
+		t.Fatalf("expected first and last imported with lines 3 and 4 reported, got %+v", result)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// indexesReady is set once ensureIndexes has finished; /readyz reports not
+// ready until then
+var indexesReady atomic.Bool
+
+// indexProgressInterval is how often a background index build logs progress
+var indexProgressInterval = 5 * time.Second
+
+// todoIndexes are the secondary indexes the list, pop, stats and sync
+// queries rely on
+func todoIndexes(background bool) []mongo.IndexModel {
+	opts := func(name string) *options.IndexOptions {
+		return options.Index().SetName(name).SetBackground(background)
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "completed", Value: 1}, {Key: "createdAt", Value: 1}}, Options: opts("completed_createdAt")},
+		{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "completed", Value: 1}}, Options: opts("owner_completed")},
+		{Keys: bson.D{{Key: "updatedAt", Value: 1}}, Options: opts("updatedAt")},
+	}
+	if uniqueDescriptions {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "description", Value: 1}, {Key: "deletedAt", Value: 1}},
+			Options: opts(uniqueDescriptionIndex).SetUnique(true).SetCollation(descriptionCollation),
+		})
+	}
+	return indexes
+}
+
+// uniqueDescriptions rejects a second live item with the same description
+// for the same owner. Configured from UNIQUE_DESCRIPTIONS in main.
+var uniqueDescriptions bool
+
+// uniqueDescriptionIndex names the unique index on description
+const uniqueDescriptionIndex = "owner_description_unique"
+
+// descriptionCollation compares descriptions case-insensitively ("Buy Milk"
+// equals "buy milk") while still telling accented letters apart
+var descriptionCollation = &options.Collation{Locale: "en_US", Strength: 2}
+
+// isDuplicateDescription reports whether err is a write rejected by the
+// unique description index. deletedAt is part of the key so soft-deleted
+// items, which keep their description, don't block re-creating it.
+func isDuplicateDescription(err error) bool {
+	return uniqueDescriptions && mongo.IsDuplicateKeyError(err)
+}
+
+// ensureIndexes creates the indexes in todoIndexes and the audit log's
+// auditIndexes, which is a no-op for indexes that already exist. Index builds
+// on a large collection can take a while, so no operation timeout is applied.
+//
+// In the default foreground mode startup waits for the build. With
+// background set (BACKGROUND_INDEXES=true) the build runs in a goroutine while
+// the server starts accepting requests, progress is logged every
+// indexProgressInterval, and /readyz answers 503 until it completes. Queries
+// served meanwhile may fall back to collection scans. On MongoDB before 4.2
+// background also asks the server for a background build, which does not
+// hold the database lock for the whole build; newer servers ignore it.
+func ensureIndexes(collection *mongo.Collection, background bool) {
+	build := func() {
+		createIndexes(context.Background(), collection, background)
+		// Queries work without the indexes, just slower, so a failed build
+		// does not keep the app out of service
+		indexesReady.Store(true)
+	}
+
+	if !background {
+		log.Info("Building indexes")
+		build()
+		return
+	}
+
+	log.Info("Building indexes in the background")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		build()
+	}()
+	go logIndexProgress(collection, done)
+}
+
+// createIndexes builds collection's indexes within ctx, logging rather than
+// returning a failure
+func createIndexes(ctx context.Context, collection *mongo.Collection, background bool) {
+	start := time.Now()
+	names, err := collection.Indexes().CreateMany(ctx, todoIndexes(background))
+	if err != nil {
+		log.Errorf("Failed to create indexes after %v: %v", time.Since(start).Round(time.Millisecond), err)
+	} else {
+		log.Infof("Indexes %v ready after %v", names, time.Since(start).Round(time.Millisecond))
+	}
+	if err := ensureCompletedTTL(ctx, collection); err != nil {
+		log.Errorf("Failed to configure the completed item TTL index: %v", err)
+	}
+	if _, err := auditCollection(collection).Indexes().CreateMany(ctx, auditIndexes(background)); err != nil {
+		log.Errorf("Failed to create the audit log indexes: %v", err)
+	}
+}
+
+// logIndexProgress periodically logs the server's progress message for the
+// index builds on collection until done is closed
+func logIndexProgress(collection *mongo.Collection, done <-chan struct{}) {
+	start := time.Now()
+	ticker := time.NewTicker(indexProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		fields := log.Fields{"elapsed": time.Since(start).Round(time.Second)}
+		ctx, cancel := opContext()
+		var result struct {
+			InProg []struct {
+				Msg string `bson:"msg"`
+			} `bson:"inprog"`
+		}
+		err := collection.Database().Client().Database("admin").RunCommand(ctx, bson.D{
+			{Key: "currentOp", Value: 1},
+			{Key: "command.createIndexes", Value: collection.Name()},
+		}).Decode(&result)
+		cancel()
+		if err == nil {
+			for _, op := range result.InProg {
+				if op.Msg != "" {
+					fields["progress"] = op.Msg
+				}
+			}
+		}
+		log.WithFields(fields).Info("Index build in progress")
+	}
+}
+
+// completedTTL is how long completed items are kept before MongoDB removes
+// them. Configured from COMPLETED_TTL in main; 0 disables the cleanup.
+var completedTTL time.Duration
+
+// completedTTLIndex names the TTL index on completedAt
+const completedTTLIndex = "completedAt_ttl"
+
+// ensureCompletedTTL makes the TTL index on completedAt match completedTTL.
+// The server's TTL monitor (which runs about once a minute) then deletes
+// completed items whose completedAt is older than the TTL; items that are
+// reopened lose completedAt and are kept. TTL removal is a hard delete, so
+// expired items don't show up in /todo/changes. An existing index is adjusted
+// with collMod when the TTL changes and dropped when the TTL is disabled.
+func ensureCompletedTTL(ctx context.Context, collection *mongo.Collection) error {
+	cur, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name               string `bson:"name"`
+		ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	}
+	if err := cur.All(ctx, &indexes); err != nil {
+		return err
+	}
+	var existing *int64
+	exists := false
+	for _, index := range indexes {
+		if index.Name == completedTTLIndex {
+			exists, existing = true, index.ExpireAfterSeconds
+		}
+	}
+
+	if completedTTL <= 0 {
+		if exists {
+			log.Info("COMPLETED_TTL is unset, dropping the completed item TTL index")
+			_, err := collection.Indexes().DropOne(ctx, completedTTLIndex)
+			return err
+		}
+		return nil
+	}
+
+	seconds := int64(completedTTL.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	switch {
+	case !exists:
+		_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "completedAt", Value: 1}},
+			Options: options.Index().SetName(completedTTLIndex).SetExpireAfterSeconds(int32(seconds)),
+		})
+	case existing == nil || *existing != seconds:
+		err = collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: completedTTLIndex}, {Key: "expireAfterSeconds", Value: seconds}}},
+		}).Err()
+	}
+	if err == nil {
+		log.Infof("Completed items expire %v after completion", time.Duration(seconds)*time.Second)
+	}
+	return err
+}
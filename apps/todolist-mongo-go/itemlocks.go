@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// serializeItemWrites makes UpdateItem, PatchItem and DeleteItem take a
+// per-item lock, so two writes to the same item on this instance run one
+// after the other instead of interleaving. Configured from
+// SERIALIZE_ITEM_WRITES in main.
+//
+// This is best effort: it only covers requests served by this process and
+// the single-item routes, so replicas, bulk endpoints and other clients of
+// the database still race. It is not a substitute for conditional updates
+// in MongoDB.
+var serializeItemWrites = true
+
+// itemLock is a mutex shared by the requests writing one item; refs counts
+// the holders and waiters so the entry can be dropped when nobody needs it
+type itemLock struct {
+	sync.Mutex
+	refs int
+}
+
+// itemLockMap hands out one itemLock per key and removes it again after the
+// last unlock, so the map only holds items currently being written
+type itemLockMap struct {
+	mu    sync.Mutex
+	locks map[string]*itemLock
+}
+
+var itemWriteLocks = &itemLockMap{locks: map[string]*itemLock{}}
+
+// lock blocks until key is free and returns the function that releases it
+func (m *itemLockMap) lock(key string) (unlock func()) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &itemLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// size reports how many keys currently have a lock entry
+func (m *itemLockMap) size() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.locks)
+}
+
+// lockItem serializes writes to the item with hex ID id in collection when
+// serializeItemWrites is on. The namespace is part of the key because
+// tenants have separate collections.
+func lockItem(collection *mongo.Collection, id string) (unlock func()) {
+	if !serializeItemWrites {
+		return func() {}
+	}
+	return itemWriteLocks.lock(collection.Database().Name() + "." + collection.Name() + "/" + id)
+}
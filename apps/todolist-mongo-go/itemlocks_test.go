@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestItemLockMap_SerializesSameKey(t *testing.T) {
+	locks := &itemLockMap{locks: map[string]*itemLock{}}
+
+	unlock := locks.lock("a")
+	acquired := make(chan struct{})
+	go func() {
+		release := locks.lock("a")
+		close(acquired)
+		release()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second writer got the lock while the first held it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// A different item is not blocked
+	locks.lock("b")()
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second writer never got the lock")
+	}
+}
+
+func TestItemLockMap_Cleanup(t *testing.T) {
+	locks := &itemLockMap{locks: map[string]*itemLock{}}
+
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock("item")
+			counter++
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if counter != 50 {
+		t.Fatalf("expected 50 serialized increments, got %d", counter)
+	}
+	if n := locks.size(); n != 0 {
+		t.Fatalf("expected no lock entries after every writer finished, got %d", n)
+	}
+}
+
+func TestWriteHandlers_ReadBodyBeforeLocking(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer func(collection *mongo.Collection, serialize bool) {
+		tododb, serializeItemWrites = collection, serialize
+	}(tododb, serializeItemWrites)
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+	serializeItemWrites = true
+
+	const id = "507f1f77bcf86cd799439011"
+	unlock := lockItem(tododb, id)
+	defer unlock()
+
+	for name, call := range map[string]func(*httptest.ResponseRecorder){
+		"UpdateItem": func(rec *httptest.ResponseRecorder) {
+			req := httptest.NewRequest(http.MethodPost, "/todo/"+id, strings.NewReader("completed=maybe"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			UpdateItem(rec, mux.SetURLVars(req, map[string]string{"id": id}))
+		},
+		"PatchItem": func(rec *httptest.ResponseRecorder) {
+			req := httptest.NewRequest(http.MethodPatch, "/todo/"+id, strings.NewReader("not json"))
+			PatchItem(rec, mux.SetURLVars(req, map[string]string{"id": id}))
+		},
+	} {
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			call(rec)
+			close(done)
+		}()
+		select {
+		case <-done:
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", name, rec.Code)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s waited for the item lock before rejecting its body", name)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// logFilePath is where main mirrors the application log
+const logFilePath = "/tmp/log/todoapp/app.log"
+
+// How often TailLogFile polls for new lines and sends a keep-alive comment
+var (
+	logTailPoll      = 500 * time.Millisecond
+	logTailKeepAlive = 15 * time.Second
+)
+
+// TailLogFile streams lines appended to the log file as Server-Sent Events,
+// like tail -f, until the client disconnects. Only lines written after the
+// request starts are sent. When the file is rotated or truncated the stream
+// sends a "rotated" event and continues from the start of the new file; while
+// the path is missing it keeps waiting for the file to reappear.
+func TailLogFile(w http.ResponseWriter, r *http.Request) {
+	stream, ok := newEventStream(w)
+	if !ok {
+		return
+	}
+
+	file, err := os.Open(logFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		writeErrorResponse(w, http.StatusNotFound, "Not Found", "Log file not found")
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to open log file: %v", err)
+		writeServerError(w, "Failed to open log file", err)
+		return
+	}
+	defer func() { file.Close() }()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		log.Errorf("Failed to seek log file: %v", err)
+		writeServerError(w, "Failed to read log file", err)
+		return
+	}
+
+	stream.start()
+
+	reader := bufio.NewReader(file)
+	var partial strings.Builder
+	poll := time.NewTicker(logTailPoll)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(logTailKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if err := stream.comment("keep-alive"); err != nil {
+				return
+			}
+		case <-poll.C:
+			for {
+				line, err := reader.ReadString('\n')
+				offset += int64(len(line))
+				if err != nil {
+					// Keep an unterminated line until the rest of it is written
+					partial.WriteString(line)
+					break
+				}
+				partial.WriteString(strings.TrimRight(line, "\r\n"))
+				if err := stream.send("", partial.String()); err != nil {
+					return
+				}
+				partial.Reset()
+			}
+
+			if rotated(file, offset) {
+				next, err := os.Open(logFilePath)
+				if err == nil {
+					log.Info("Log file rotated, following the new file")
+					file.Close()
+					file, offset = next, 0
+					reader.Reset(file)
+					partial.Reset()
+					if err := stream.send("rotated", ""); err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// rotated reports whether the log path now names a different file than the
+// open one, or the open file was truncated below what has been read
+func rotated(file *os.File, offset int64) bool {
+	current, err := os.Stat(logFilePath)
+	if err != nil {
+		return false
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(current, open) || open.Size() < offset
+}
+
+// trimLogFile archives the log file at path to path.1, replacing any earlier
+// archive, when it has grown past maxBytes, so the next open starts a fresh
+// file. It reports whether the file was archived; a missing file is not an
+// error.
+func trimLogFile(path string, maxBytes int64) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.Size() <= maxBytes {
+		return false, nil
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
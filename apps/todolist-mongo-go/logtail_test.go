@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrimLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	if trimmed, err := trimLogFile(path, 10); trimmed || err != nil {
+		t.Fatalf("missing file: got %v, %v", trimmed, err)
+	}
+
+	os.WriteFile(path, []byte("short\n"), 0644)
+	if trimmed, err := trimLogFile(path, 10); trimmed || err != nil {
+		t.Fatalf("small file: got %v, %v", trimmed, err)
+	}
+
+	os.WriteFile(path+".1", []byte("older archive\n"), 0644)
+	os.WriteFile(path, []byte(strings.Repeat("x", 11)), 0644)
+	if trimmed, err := trimLogFile(path, 10); !trimmed || err != nil {
+		t.Fatalf("large file: got %v, %v", trimmed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the log to be moved away, got %v", err)
+	}
+	if archived, _ := os.ReadFile(path + ".1"); string(archived) != strings.Repeat("x", 11) {
+		t.Fatalf("unexpected archive contents %q", archived)
+	}
+}
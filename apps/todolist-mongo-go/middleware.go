@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// gzipMinSize is the smallest response body, in bytes, that gzipMiddleware
+// compresses. Configured from GZIP_MIN_SIZE in main.
+var gzipMinSize = 1024
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		if strings.TrimSpace(fields[0]) != "gzip" {
+			continue
+		}
+		if len(fields) > 1 && strings.ReplaceAll(fields[1], " ", "") == "q=0" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// gzipMiddleware compresses responses larger than gzipMinSize for clients
+// that accept gzip. Server-sent event streams and responses that already set
+// a Content-Encoding are passed through untouched.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: gzipMinSize, status: http.StatusOK}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough to be worth compressing
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+	committed   bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.committed {
+		return
+	}
+	g.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified || g.skipCompression() {
+		g.startPassthrough()
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.passthrough {
+		return g.ResponseWriter.Write(p)
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	if g.skipCompression() {
+		g.startPassthrough()
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minSize {
+		if err := g.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush lets streaming handlers push data immediately. A response that is
+// still being buffered is committed uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	} else if !g.passthrough {
+		g.startPassthrough()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the gzip stream, or writes out a body that stayed below the
+// compression threshold
+func (g *gzipResponseWriter) Close() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	if !g.passthrough {
+		g.startPassthrough()
+	}
+	return nil
+}
+
+func (g *gzipResponseWriter) skipCompression() bool {
+	h := g.Header()
+	return h.Get("Content-Encoding") != "" ||
+		strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+}
+
+func (g *gzipResponseWriter) startPassthrough() {
+	g.passthrough = true
+	g.committed = true
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) > 0 {
+		g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *gzipResponseWriter) startGzip() error {
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.committed = true
+	g.ResponseWriter.WriteHeader(g.status)
+
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	_, err := g.gz.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+// gunzipRequestMiddleware decompresses request bodies sent with
+// Content-Encoding: gzip before handlers read them. The body is inflated up
+// front, at most maxSize bytes, so a zip bomb is cut off early and the
+// client gets a clear status: 400 for a malformed gzip stream and 413 when the
+// decompressed body is larger than maxSize. Other encodings are rejected with
+// 415. A maxSize of 0 disables the middleware.
+func gunzipRequestMiddleware(maxSize int64, next http.Handler) http.Handler {
+	if maxSize <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if encoding != "gzip" {
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "Unsupported Media Type", "Only gzip request bodies are supported")
+			return
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Malformed gzip request body")
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(gz, maxSize+1))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Malformed gzip request body")
+			return
+		}
+		if int64(len(body)) > maxSize {
+			log.Warnf("Rejecting %s %s: decompressed body exceeds %d bytes", r.Method, r.URL.Path, maxSize)
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", fmt.Sprintf("Decompressed request body exceeds %d bytes", maxSize))
+			return
+		}
+
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Del("Content-Encoding")
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trailingSlashMiddleware rewrites paths such as /todo/ to /todo so both forms
+// reach the same route. The root and the static /resources/ tree are left as is.
+func trailingSlashMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if len(p) > 1 && strings.HasSuffix(p, "/") && !strings.HasPrefix(p, "/resources/") {
+			r.URL.Path = strings.TrimRight(p, "/")
+			if r.URL.Path == "" {
+				r.URL.Path = "/"
+			}
+			r.URL.RawPath = ""
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireDatabaseMiddleware answers 503 for the /todo and /admin routes while the
+// collection handle is not initialized, instead of letting handlers panic on
+// a nil collection
+func requireDatabaseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tododb == nil && (strings.HasPrefix(r.URL.Path, "/todo") || strings.HasPrefix(r.URL.Path, "/admin/")) {
+			log.Warnf("Rejecting %s %s: database not ready", r.Method, r.URL.Path)
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Service Unavailable", "Database not ready")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiKeyHeader carries the key required by admin routes
+const apiKeyHeader = "X-API-Key"
+
+// apiKey guards admin routes such as POST /todo/transfer. Configured from
+// API_KEY in main; when empty those routes answer 403.
+var apiKey string
+
+// checkAPIKey reports whether r carries the API_KEY, answering 403 (admin
+// features disabled) or 401 and returning false when not
+func checkAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if apiKey == "" {
+		writeErrorResponse(w, http.StatusForbidden, "Forbidden", "Admin endpoints are disabled; set API_KEY to enable them")
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(apiKeyHeader)), []byte(apiKey)) != 1 {
+		log.Warnf("Rejecting %s %s: invalid API key", r.Method, r.URL.Path)
+		writeErrorResponse(w, http.StatusUnauthorized, "Unauthorized", "Missing or invalid "+apiKeyHeader+" header")
+		return false
+	}
+	return true
+}
+
+// requireAPIKey rejects requests whose X-API-Key does not match API_KEY
+func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if checkAPIKey(w, r) {
+			next(w, r)
+		}
+	}
+}
+
+// concurrencyLimitMiddleware bounds the number of requests being served at
+// once to limit, answering 503 with Retry-After while every slot is taken.
+// Unlike a rate limit it caps in-flight work, so slow requests hold their slot
+// until they finish. The health and readiness probes are exempt so they keep
+// working under load, and the long-lived streamingPaths are left to
+// limitStreams.
+// A limit of 0 disables the middleware.
+func concurrencyLimitMiddleware(limit int, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProbePath(r.URL.Path) || streamingPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			log.Warnf("Rejecting %s %s: %d requests already in flight", r.Method, r.URL.Path, limit)
+			w.Header().Set("Retry-After", "1")
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Service Unavailable", "Server is busy, retry shortly")
+		}
+	})
+}
+
+// streamingPaths are the routes wrapped in limitStreams. They hold their
+// connection open for a long time, so they don't take general request slots.
+var streamingPaths = map[string]bool{
+	"/log/tail":          true,
+	"/todo/overdue/wait": true,
+}
+
+// streamSlots bounds concurrent streaming connections. Configured from
+// MAX_STREAMING_CONNECTIONS in main; nil means unlimited.
+var streamSlots chan struct{}
+
+// activeStreams is the number of streaming connections currently open
+var activeStreams int64
+
+// limitStreams admits a Server-Sent Events, log tail or long-poll request
+// only while a streamSlots slot is free, answering 503 with Retry-After
+// otherwise, and logs the number of open streams as connections come and go
+func limitStreams(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if slots := streamSlots; slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				log.Warnf("Rejecting %s %s: %d streaming connections already open", r.Method, r.URL.Path, cap(slots))
+				w.Header().Set("Retry-After", "5")
+				writeErrorResponse(w, http.StatusServiceUnavailable, "Service Unavailable", "Too many streaming connections, retry shortly")
+				return
+			}
+		}
+
+		active := atomic.AddInt64(&activeStreams, 1)
+		log.WithFields(log.Fields{"path": r.URL.Path, "active": active}).Info("Streaming connection opened")
+		defer func() {
+			active := atomic.AddInt64(&activeStreams, -1)
+			log.WithFields(log.Fields{"path": r.URL.Path, "active": active}).Info("Streaming connection closed")
+		}()
+		next(w, r)
+	}
+}
+
+// Request counters maintained by accessLogMiddleware and reported by /status
+var (
+	requestsTotal    int64
+	requestsInFlight int64
+	requestErrors    int64
+)
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// accessLogMiddleware logs each request and updates the request counters
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		atomic.AddInt64(&requestsTotal, 1)
+		atomic.AddInt64(&requestsInFlight, 1)
+		defer atomic.AddInt64(&requestsInFlight, -1)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r, _ = withRouteName(r)
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= 400 {
+			atomic.AddInt64(&requestErrors, 1)
+		}
+		log.WithFields(log.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"route":    routeOf(r),
+			"client":   clientIP(r),
+			"status":   rec.status,
+			"duration": time.Since(start).String(),
+		}).Debug("Handled request")
+	})
+}
+
+// routeNameKey is the context key of the *routeName filled in by
+// routeNameMiddleware
+type routeNameKey struct{}
+
+// routeName holds the name of the route a request matched. accessLogMiddleware
+// creates it before routing so the name set by routeNameMiddleware, which
+// runs after the router matched, is visible to the outer middleware too.
+type routeName struct {
+	name string
+}
+
+// withRouteName returns r with an empty routeName holder in its context
+func withRouteName(r *http.Request) (*http.Request, *routeName) {
+	holder := &routeName{}
+	return r.WithContext(context.WithValue(r.Context(), routeNameKey{}, holder)), holder
+}
+
+// routeOf returns the name of the route r matched, or "" before routing or
+// when no named route matched
+func routeOf(r *http.Request) string {
+	if holder, ok := r.Context().Value(routeNameKey{}).(*routeName); ok {
+		return holder.name
+	}
+	return ""
+}
+
+// activeRoutes maps the goroutine serving each routed request to the
+// route's name, for routeHook
+var activeRoutes sync.Map
+
+// goroutineID returns the current goroutine's id, parsed from the
+// "goroutine N [running]:" header of its stack trace
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(fields[1], 10, 64)
+	return id
+}
+
+// routeHook adds a route field to every log line written on a goroutine
+// that is serving a routed request, so handlers and the helpers they call
+// keep logging through the package-level logger. Lines logged from
+// goroutines a handler starts are not tagged.
+type routeHook struct{}
+
+func (routeHook) Levels() []log.Level { return log.AllLevels }
+
+func (routeHook) Fire(entry *log.Entry) error {
+	if _, ok := entry.Data["route"]; ok {
+		return nil
+	}
+	if name, ok := activeRoutes.Load(goroutineID()); ok {
+		entry.Data["route"] = name
+	}
+	return nil
+}
+
+// routeNameMiddleware records the name of the matched mux route (set with
+// .Name in main) for routeOf and for routeHook, which tags the log lines
+// written while the request is handled. Installed with router.Use, so it only
+// runs for requests that matched a route.
+func routeNameMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			holder, ok := r.Context().Value(routeNameKey{}).(*routeName)
+			if !ok {
+				r, holder = withRouteName(r)
+			}
+			holder.name = route.GetName()
+			id := goroutineID()
+			activeRoutes.Store(id, holder.name)
+			defer activeRoutes.Delete(id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isStaticPath reports whether p belongs to the public web UI routes
+func isStaticPath(p string) bool {
+	return p == "/" || p == "/favicon.ico" || strings.HasPrefix(p, "/resources/")
+}
+
+// corsByRouteGroup sends the public web UI routes through static and all
+// other (API) routes through api, so each group can have its own CORS policy
+func corsByRouteGroup(static, api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStaticPath(r.URL.Path) {
+			static.ServeHTTP(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	const limit = 2
+	release := make(chan struct{})
+	started := make(chan struct{}, limit)
+	handler := concurrencyLimitMiddleware(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	codes := make([]int, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/todo", nil))
+			codes[i] = rec.Code
+		}(i)
+	}
+	for i := 0; i < limit; i++ {
+		<-started
+	}
+
+	// Every slot is held, so the next request is turned away
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/todo", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while saturated, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+
+	// Slots are released once requests finish
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/todo", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after release, got %d", rec.Code)
+	}
+}
+
+func TestLimitStreams(t *testing.T) {
+	prev := streamSlots
+	streamSlots = make(chan struct{}, 1)
+	defer func() { streamSlots = prev }()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := limitStreams(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/log/tail", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/log/tail", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while the stream slot is held, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("held stream: expected 200, got %d", code)
+	}
+	if n := atomic.LoadInt64(&activeStreams); n != 0 {
+		t.Fatalf("expected no active streams after close, got %d", n)
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestGunzipRequestMiddleware(t *testing.T) {
+	var got []byte
+	handler := gunzipRequestMiddleware(64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+	serve := func(body []byte, encoding string) int {
+		req := httptest.NewRequest(http.MethodPost, "/todo", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(gzipBytes(t, []byte(`{"description":"buy milk"}`)), "gzip"); code != http.StatusOK || string(got) != `{"description":"buy milk"}` {
+		t.Fatalf("gzip body: got %d %q", code, got)
+	}
+	if code := serve([]byte(`{"description":"plain"}`), ""); code != http.StatusOK || string(got) != `{"description":"plain"}` {
+		t.Fatalf("plain body: got %d %q", code, got)
+	}
+	if code := serve([]byte("not gzip"), "gzip"); code != http.StatusBadRequest {
+		t.Fatalf("malformed gzip: expected 400, got %d", code)
+	}
+	truncated := gzipBytes(t, bytes.Repeat([]byte("a"), 32))
+	if code := serve(truncated[:len(truncated)-6], "gzip"); code != http.StatusBadRequest {
+		t.Fatalf("truncated gzip: expected 400, got %d", code)
+	}
+	if code := serve(gzipBytes(t, bytes.Repeat([]byte("a"), 1<<20)), "gzip"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: expected 413, got %d", code)
+	}
+	if code := serve([]byte("x"), "br"); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("unsupported encoding: expected 415, got %d", code)
+	}
+}
+
+func TestRouteNameMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(routeNameMiddleware)
+	var seen string
+	router.HandleFunc("/todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		seen = routeOf(r)
+	}).Name("GetItem")
+
+	req, holder := withRouteName(httptest.NewRequest(http.MethodGet, "/todo/abc", nil))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "GetItem" {
+		t.Errorf("handler saw route %q, want GetItem", seen)
+	}
+	if holder.name != "GetItem" {
+		t.Errorf("outer holder has route %q, want GetItem", holder.name)
+	}
+
+	req, holder = withRouteName(httptest.NewRequest(http.MethodGet, "/nothing/here", nil))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if holder.name != "" {
+		t.Errorf("unmatched request has route %q, want empty", holder.name)
+	}
+}
+
+// captureHook records the route field of each log line
+type captureHook struct {
+	mu     sync.Mutex
+	routes []interface{}
+}
+
+func (h *captureHook) Levels() []log.Level { return log.AllLevels }
+
+func (h *captureHook) Fire(entry *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routes = append(h.routes, entry.Data["route"])
+	return nil
+}
+
+func TestRouteHook(t *testing.T) {
+	logger := log.StandardLogger()
+	prev := logger.ReplaceHooks(make(log.LevelHooks))
+	defer logger.ReplaceHooks(prev)
+	capture := &captureHook{}
+	log.AddHook(routeHook{})
+	log.AddHook(capture)
+
+	router := mux.NewRouter()
+	router.Use(routeNameMiddleware)
+	router.HandleFunc("/todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// a helper logging without the request still gets the route
+		log.Info("inside the handler")
+	}).Name("GetItem")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todo/abc", nil))
+	log.Info("after the request")
+
+	if len(capture.routes) != 2 || capture.routes[0] != "GetItem" || capture.routes[1] != nil {
+		t.Fatalf("expected route GetItem then none, got %v", capture.routes)
+	}
+}
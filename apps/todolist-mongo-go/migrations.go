@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runMigrations applies the pending schema migrations at startup
+func runMigrations(collection *mongo.Collection) error {
+	log.Info("Running migrations")
+	_, err := migrateSchema(collection)
+	return err
+}
+
+// backfillTimestamps sets createdAt on documents that predate it from the
+// creation time embedded in their ObjectID (the same value as
+// objID.Timestamp()), and updatedAt to the same value when it is missing too.
+// The pipeline update runs server-side in a single UpdateMany.
+func backfillTimestamps(collection *mongo.Collection) (int64, error) {
+	filter := bson.M{"createdAt": bson.M{"$exists": false}}
+	idTime := bson.M{"$toDate": "$_id"}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"createdAt": idTime,
+			"updatedAt": bson.M{"$ifNull": bson.A{"$updatedAt", idTime}},
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Errorf("Failed to backfill timestamps: %v", err)
+		return 0, err
+	}
+	log.Infof("Backfilled timestamps on %d documents", result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// backfillStatus sets status on documents that predate it from completed, in
+// a single server-side UpdateMany
+func backfillStatus(collection *mongo.Collection) (int64, error) {
+	filter := bson.M{"status": bson.M{"$exists": false}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$completed", true}}, statusDone, statusPending}},
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Errorf("Failed to backfill status: %v", err)
+		return 0, err
+	}
+	log.Infof("Backfilled status on %d documents", result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+// descriptionKeyBatch is how many documents backfillDescriptionKeys updates
+// per BulkWrite
+const descriptionKeyBatch = 500
+
+// backfillDescriptionKeys sets descriptionKey on documents written before it
+// existed. The normalization (Unicode lowercasing, whitespace collapsing)
+// can't be expressed in an update pipeline, so documents are read and
+// updated in batches from the app; each update is guarded on the description
+// it was computed from so a concurrent edit is not overwritten.
+func backfillDescriptionKeys(collection *mongo.Collection) (int64, error) {
+	ctx := context.Background()
+	filter := bson.M{"descriptionKey": bson.M{"$exists": false}, "description": bson.M{"$type": "string"}}
+	opts := options.Find().SetProjection(bson.M{"description": 1})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Errorf("Failed to backfill description keys: %v", err)
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var updated int64
+	var batch []mongo.WriteModel
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := collection.BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return err
+		}
+		updated += result.ModifiedCount
+		batch = batch[:0]
+		return nil
+	}
+	for cur.Next(ctx) {
+		var doc struct {
+			Id          primitive.ObjectID `bson:"_id"`
+			Description string             `bson:"description"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			log.Warnf("Skipping undecodable document: %v", err)
+			continue
+		}
+		batch = append(batch, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.Id, "description": doc.Description}).
+			SetUpdate(bson.M{"$set": bson.M{"descriptionKey": descriptionKey(doc.Description)}}))
+		if len(batch) == descriptionKeyBatch {
+			if err := flush(); err != nil {
+				log.Errorf("Failed to backfill description keys: %v", err)
+				return updated, err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		log.Errorf("Failed to backfill description keys: %v", err)
+		return updated, err
+	}
+	if err := flush(); err != nil {
+		log.Errorf("Failed to backfill description keys: %v", err)
+		return updated, err
+	}
+	log.Infof("Backfilled description keys on %d documents", updated)
+	return updated, nil
+}
+
+// IntegrityIssue describes one problem found by VerifyIntegrity
+type IntegrityIssue struct {
+	Id      primitive.ObjectID `json:"id"`
+	Field   string             `json:"field"`
+	Problem string             `json:"problem"`
+	Fixed   bool               `json:"fixed"`
+}
+
+// IntegrityReport summarizes a VerifyIntegrity run
+type IntegrityReport struct {
+	Scanned int64            `json:"scanned"`
+	Found   int              `json:"found"`
+	Fixed   int              `json:"fixed"`
+	Issues  []IntegrityIssue `json:"issues"`
+}
+
+// untitledDescription replaces empty descriptions when repairing
+const untitledDescription = "(untitled)"
+
+// checkDocument lists the integrity problems of one document together with
+// the $set/$unset fields that would repair them
+func checkDocument(doc bson.M) (issues []IntegrityIssue, set bson.M, unset bson.M) {
+	id, _ := doc["_id"].(primitive.ObjectID)
+	set, unset = bson.M{}, bson.M{}
+
+	if description, ok := doc["description"].(string); !ok || strings.TrimSpace(description) == "" {
+		issues = append(issues, IntegrityIssue{Id: id, Field: "description", Problem: "missing or empty"})
+		set["description"] = untitledDescription
+		set["descriptionKey"] = descriptionKey(untitledDescription)
+	}
+	for _, field := range []string{"createdAt", "updatedAt"} {
+		if _, ok := doc[field].(primitive.DateTime); !ok {
+			issues = append(issues, IntegrityIssue{Id: id, Field: field, Problem: "missing or not a date"})
+			set[field] = id.Timestamp()
+		}
+	}
+	if priority, present := doc["priority"]; present {
+		if p, ok := priority.(string); !ok || !validPriorities[p] {
+			issues = append(issues, IntegrityIssue{Id: id, Field: "priority", Problem: fmt.Sprintf("invalid value %v", priority)})
+			unset["priority"] = ""
+		}
+	}
+	return issues, set, unset
+}
+
+// VerifyIntegrity scans every document for missing required fields and
+// invalid values, and repairs them when repair=true. Debug-only: registered
+// when debugEndpointsEnabled.
+func VerifyIntegrity(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	repair := r.FormValue("repair") == "true"
+	log.WithFields(log.Fields{"repair": repair}).Info("Verifying data integrity")
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		log.Errorf("Failed to scan todo items: %v", err)
+		writeServerError(w, "Failed to verify todo items", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	report := IntegrityReport{Issues: []IntegrityIssue{}}
+	for cur.Next(ctx) {
+		report.Scanned++
+		var doc bson.M
+		if err := cur.Decode(&doc); err != nil {
+			log.Warnf("Skipping undecodable document: %v", err)
+			continue
+		}
+
+		issues, set, unset := checkDocument(doc)
+		if len(issues) == 0 {
+			continue
+		}
+		if repair {
+			update := bson.M{}
+			if len(set) > 0 {
+				update["$set"] = set
+			}
+			if len(unset) > 0 {
+				update["$unset"] = unset
+			}
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc["_id"]}, update); err != nil {
+				log.Errorf("Failed to repair document %v: %v", doc["_id"], err)
+			} else {
+				for i := range issues {
+					issues[i].Fixed = true
+				}
+				report.Fixed += len(issues)
+			}
+		}
+		report.Found += len(issues)
+		report.Issues = append(report.Issues, issues...)
+	}
+	if err := cur.Err(); err != nil {
+		log.Errorf("Cursor error: %v", err)
+		writeServerError(w, "Failed to verify todo items", err)
+		return
+	}
+
+	log.Infof("Integrity check scanned %d documents: %d issues found, %d fixed", report.Scanned, report.Found, report.Fixed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckDocument(t *testing.T) {
+	id := primitive.NewObjectID()
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	issues, _, _ := checkDocument(bson.M{"_id": id, "description": "ok", "createdAt": now, "updatedAt": now, "priority": "low"})
+	if len(issues) != 0 {
+		t.Fatalf("expected a clean document, got %+v", issues)
+	}
+
+	issues, set, unset := checkDocument(bson.M{"_id": id, "description": "  ", "priority": "urgent"})
+	if len(issues) != 4 {
+		t.Fatalf("expected 4 issues, got %+v", issues)
+	}
+	if set["description"] != untitledDescription || set["createdAt"] != id.Timestamp() {
+		t.Fatalf("unexpected repair $set: %v", set)
+	}
+	if _, ok := unset["priority"]; !ok {
+		t.Fatalf("expected priority to be unset, got %v", unset)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// todoItemsXML is the XML document for a list of items
+type todoItemsXML struct {
+	XMLName xml.Name         `xml:"todos"`
+	Items   []*TodoItemModel `xml:"todo"`
+}
+
+// wantsXML reports whether the Accept header prefers application/xml (or
+// text/xml) over JSON. The */* and application/* wildcards count for JSON, the
+// default, at their q value. JSON wins ties and when neither is listed.
+func wantsXML(r *http.Request) bool {
+	var jsonQ, xmlQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(fields[0])) {
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		case "application/xml", "text/xml":
+			xmlQ = max(xmlQ, q)
+		}
+	}
+	return xmlQ > jsonQ
+}
+
+// writeNegotiated encodes v as XML when the client asked for it and as JSON otherwise
+func writeNegotiated(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Add("Vary", "Accept")
+	if wantsXML(r) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(v)
+		return
+	}
+	writeJSON(w, v)
+}
+
+// writeItems writes a list of items, wrapping it in <todos> for XML. An empty
+// list is written as [] unless the client asked for empty_as_404=true.
+func writeItems(w http.ResponseWriter, r *http.Request, items []*TodoItemModel) {
+	if len(items) == 0 {
+		if value := r.URL.Query().Get("empty_as_404"); value != "" {
+			emptyAs404, err := strconv.ParseBool(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid empty_as_404 value. Must be true or false")
+				return
+			}
+			if emptyAs404 {
+				writeErrorResponse(w, http.StatusNotFound, "Not Found", "No todo items match")
+				return
+			}
+		}
+	}
+	if wantsXML(r) {
+		writeNegotiated(w, r, todoItemsXML{Items: items})
+		return
+	}
+	writeNegotiated(w, r, items)
+}
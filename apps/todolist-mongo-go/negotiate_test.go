@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWantsXML(t *testing.T) {
+	cases := map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"application/json":                  false,
+		"application/xml":                   true,
+		"text/xml":                          true,
+		"application/json, application/xml": false,
+		"application/xml, application/json;q=0.5": true,
+		"application/xml;q=0.1, */*":              false,
+		"application/xml;q=0.5, application/*":    false,
+		"application/xml, */*;q=0.8":              true,
+	}
+	for accept, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/todo", nil)
+		req.Header.Set("Accept", accept)
+		if got := wantsXML(req); got != want {
+			t.Errorf("wantsXML(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestWriteItems_XML(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("507f1f77bcf86cd799439011")
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	items := []*TodoItemModel{{
+		Id:          id,
+		Description: "buy milk",
+		Tags:        []string{"home", "shop"},
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/todo", nil)
+	req.Header.Set("Accept", "application/xml")
+	rec := httptest.NewRecorder()
+	writeItems(rec, req, items)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	want := `<todos><todo><id>507f1f77bcf86cd799439011</id><description>buy milk</description>` +
+		`<completed>false</completed><tags><tag>home</tag><tag>shop</tag></tags>` +
+		`<createdAt>2024-01-02T03:04:05Z</createdAt><updatedAt>2024-01-02T03:04:05Z</updatedAt></todo></todos>`
+	if body := rec.Body.String(); !strings.Contains(body, want) {
+		t.Fatalf("unexpected XML\n got: %s\nwant: %s", body, want)
+	}
+}
+
+func TestWriteItems_EmptyAs404(t *testing.T) {
+	tests := []struct {
+		query  string
+		items  []*TodoItemModel
+		status int
+		body   string
+	}{
+		{"", []*TodoItemModel{}, http.StatusOK, "[]\n"},
+		{"?empty_as_404=false", []*TodoItemModel{}, http.StatusOK, "[]\n"},
+		{"?empty_as_404=true", []*TodoItemModel{}, http.StatusNotFound, ""},
+		{"?empty_as_404=yes", []*TodoItemModel{}, http.StatusBadRequest, ""},
+		{"?empty_as_404=true", []*TodoItemModel{{Description: "buy milk"}}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeItems(rec, httptest.NewRequest(http.MethodGet, "/todo"+tt.query, nil), tt.items)
+		if rec.Code != tt.status {
+			t.Errorf("%s with %d items: expected %d, got %d", tt.query, len(tt.items), tt.status, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.query, tt.body, rec.Body.String())
+		}
+	}
+}
+
+func TestGetOldestItem_XML(t *testing.T) {
+	collection := setupTestCollection(t)
+	now := time.Now().UTC()
+	if _, err := collection.InsertOne(context.TODO(), bson.M{"description": "buy milk", "completed": false, "createdAt": now, "updatedAt": now}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/oldest", nil)
+	req.Header.Set("Accept", "application/xml")
+	rec := httptest.NewRecorder()
+	GetOldestItem(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(strings.TrimPrefix(body, xml.Header), "<todo>") || !strings.Contains(body, "<description>buy milk</description>") {
+		t.Fatalf("unexpected XML: %s", body)
+	}
+}
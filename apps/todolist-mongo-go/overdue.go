@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Long-poll limits for GET /todo/overdue/wait
+const (
+	overdueWaitDefault = 30 * time.Second
+	overdueWaitMax     = 2 * time.Minute
+)
+
+// overdueWaitPoll is how often GetOverdueWait re-checks for overdue items
+var overdueWaitPoll = 2 * time.Second
+
+// overdueFilter matches incomplete items whose due date has passed
+func overdueFilter(now time.Time) bson.M {
+	return notDeleted(actionable(bson.M{
+		"dueDate": bson.M{"$lt": now},
+	}))
+}
+
+// GetOverdueWait long-polls for overdue items, for clients that can't use
+// Server-Sent Events. It answers immediately with the overdue items (most
+// overdue first, at most 50) if there are any, otherwise re-checks every
+// overdueWaitPoll until timeout (default 30s, at most 2m) and answers 204 if
+// none appear. A client that disconnects ends the wait. Each waiting request
+// holds a MAX_STREAMING_CONNECTIONS slot for the whole wait.
+func GetOverdueWait(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	timeout := overdueWaitDefault
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || d > overdueWaitMax {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid timeout value. Must be a duration between 0s and "+overdueWaitMax.String())
+			return
+		}
+		timeout = d
+	}
+
+	log.WithFields(log.Fields{"timeout": timeout}).Info("Waiting for overdue TodoItems")
+
+	findOptions := options.Find().SetSort(bson.M{"dueDate": 1}).SetLimit(50)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(overdueWaitPoll)
+	defer poll.Stop()
+
+	for {
+		items, err := findTodoItems(collection, overdueFilter(time.Now()), findOptions)
+		if err != nil {
+			writeServerError(w, "Failed to retrieve overdue todo items", err)
+			return
+		}
+		if len(items) > 0 {
+			writeItems(w, r, items)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-poll.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetOverdueWait_InvalidTimeout(t *testing.T) {
+	for _, query := range []string{"?timeout=soon", "?timeout=-1s", "?timeout=1h"} {
+		rec := httptest.NewRecorder()
+		GetOverdueWait(rec, httptest.NewRequest(http.MethodGet, "/todo/overdue/wait"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestGetOverdueWait(t *testing.T) {
+	collection := setupTestCollection(t)
+
+	rec := httptest.NewRecorder()
+	GetOverdueWait(rec, httptest.NewRequest(http.MethodGet, "/todo/overdue/wait?timeout=0s", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without overdue items, got %d", rec.Code)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := collection.InsertOne(context.TODO(), bson.M{"description": "late", "completed": false, "dueDate": past}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	GetOverdueWait(rec, httptest.NewRequest(http.MethodGet, "/todo/overdue/wait?timeout=5s", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with an overdue item, got %d", rec.Code)
+	}
+}
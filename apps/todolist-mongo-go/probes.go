@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Probe paths for Healthz and Readyz, configured from HEALTH_PATH and
+// READINESS_PATH in main for ingresses that reserve the defaults
+var (
+	healthPath    = "/healthz"
+	readinessPath = "/readyz"
+)
+
+// isProbePath reports whether path is one of the configured probe paths
+func isProbePath(path string) bool {
+	return path == healthPath || path == readinessPath
+}
+
+// checkProbePaths validates the configured probe paths against the routes
+// registered on router. Each must be an absolute path without a trailing
+// slash or route variables, outside /todo (which answers 503 while the
+// database is down), distinct from the other, and not also matched by any
+// other route, which would otherwise be shadowed or shadow the probe.
+func checkProbePaths(router *mux.Router) error {
+	probes := []struct{ env, path, route string }{
+		{"HEALTH_PATH", healthPath, "Healthz"},
+		{"READINESS_PATH", readinessPath, "Readyz"},
+	}
+	for i, probe := range probes {
+		switch {
+		case !strings.HasPrefix(probe.path, "/") || probe.path == "/" || strings.HasSuffix(probe.path, "/"):
+			return fmt.Errorf("%s %q must start with / and must not end with /", probe.env, probe.path)
+		case strings.ContainsAny(probe.path, "{}?#"):
+			return fmt.Errorf("%s %q must be a plain path", probe.env, probe.path)
+		case strings.HasPrefix(probe.path, "/todo"):
+			return fmt.Errorf("%s %q collides with the /todo API", probe.env, probe.path)
+		}
+		for _, other := range probes[:i] {
+			if other.path == probe.path {
+				return fmt.Errorf("%s and %s are both %q", other.env, probe.env, probe.path)
+			}
+		}
+
+		req, err := http.NewRequest("GET", probe.path, nil)
+		if err != nil {
+			return fmt.Errorf("%s %q: %v", probe.env, probe.path, err)
+		}
+		err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			var match mux.RouteMatch
+			if name := route.GetName(); name != probe.route && name != "Healthz" && name != "Readyz" && route.Match(req, &match) {
+				return fmt.Errorf("%s %q collides with route %s", probe.env, probe.path, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
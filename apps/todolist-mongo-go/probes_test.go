@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCheckProbePaths(t *testing.T) {
+	defer func(health, readiness string) { healthPath, readinessPath = health, readiness }(healthPath, readinessPath)
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	tests := []struct {
+		health, readiness string
+		ok                bool
+	}{
+		{"/healthz", "/readyz", true},
+		{"/ops/health", "/ops/ready", true},
+		{"healthz", "/readyz", false},
+		{"/healthz/", "/readyz", false},
+		{"/", "/readyz", false},
+		{"/health/{x}", "/readyz", false},
+		{"/todo/healthz", "/readyz", false},
+		{"/todo", "/readyz", false},
+		{"/healthz", "/healthz", false},
+		{"/status", "/readyz", false},
+		{"/healthz", "/resources/ready", false},
+	}
+	for _, tt := range tests {
+		healthPath, readinessPath = tt.health, tt.readiness
+		router := mux.NewRouter()
+		router.PathPrefix("/resources/").HandlerFunc(noop).Name("StaticFiles")
+		router.HandleFunc(healthPath, noop).Methods("GET").Name("Healthz")
+		router.HandleFunc(readinessPath, noop).Methods("GET").Name("Readyz")
+		router.HandleFunc("/status", noop).Methods("GET").Name("GetStatus")
+		router.HandleFunc("/todo/{id}", noop).Methods("GET").Name("GetItem")
+
+		if err := checkProbePaths(router); (err == nil) != tt.ok {
+			t.Errorf("%q, %q: got %v, want ok %v", tt.health, tt.readiness, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TimeRange bounds a date field; both ends are optional RFC3339 timestamps
+// and inclusive
+type TimeRange struct {
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+}
+
+// QueryRequest is the body of POST /todo/query. Every criterion is optional
+// and they are combined with AND.
+type QueryRequest struct {
+	Text      string     `json:"text,omitempty"`
+	Regex     bool       `json:"regex,omitempty"`
+	Completed *bool      `json:"completed,omitempty"`
+	Status    []string   `json:"status,omitempty"`
+	Priority  []string   `json:"priority,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	TagMode   string     `json:"tagMode,omitempty"`
+	Due       *TimeRange `json:"due,omitempty"`
+	Created   *TimeRange `json:"created,omitempty"`
+	Limit     int64      `json:"limit,omitempty"`
+	Offset    int64      `json:"offset,omitempty"`
+}
+
+// QueryResult is one page of POST /todo/query results
+type QueryResult struct {
+	Items  []*TodoItemModel `json:"items"`
+	Total  int64            `json:"total"`
+	Limit  int64            `json:"limit"`
+	Offset int64            `json:"offset"`
+}
+
+// timeRangeCondition turns a TimeRange on field into a condition, reporting
+// problems against name.after and name.before
+func timeRangeCondition(tr *TimeRange, field, name string) (bson.M, []FieldError) {
+	var errs []FieldError
+	bounds := bson.M{}
+	var start, end time.Time
+	var err error
+	if tr.After != "" {
+		if start, err = time.Parse(time.RFC3339, tr.After); err != nil {
+			errs = append(errs, FieldError{Field: name + ".after", Message: "must be an RFC3339 timestamp"})
+		} else {
+			bounds["$gte"] = start
+		}
+	}
+	if tr.Before != "" {
+		if end, err = time.Parse(time.RFC3339, tr.Before); err != nil {
+			errs = append(errs, FieldError{Field: name + ".before", Message: "must be an RFC3339 timestamp"})
+		} else {
+			bounds["$lte"] = end
+		}
+	}
+	if len(errs) == 0 && tr.After != "" && tr.Before != "" && end.Before(start) {
+		errs = append(errs, FieldError{Field: name + ".before", Message: "must not be earlier than " + name + ".after"})
+	}
+	if len(errs) > 0 || len(bounds) == 0 {
+		return nil, errs
+	}
+	return bson.M{field: bounds}, nil
+}
+
+// filter validates the whole request and builds the Mongo filter, returning
+// every problem found rather than stopping at the first. Limit and offset
+// are checked and defaulted in place.
+func (q *QueryRequest) filter() (bson.M, []FieldError) {
+	var errs []FieldError
+	var conditions bson.A
+
+	if q.Text != "" {
+		condition, err := searchCondition(q.Text, q.Regex)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "text", Message: err.Error()})
+		} else {
+			conditions = append(conditions, condition)
+		}
+	} else if q.Regex {
+		errs = append(errs, FieldError{Field: "regex", Message: "regex requires text"})
+	}
+
+	if q.Completed != nil {
+		conditions = append(conditions, bson.M{"completed": *q.Completed})
+	}
+
+	if len(q.Status) > 0 {
+		condition, err := parseStatusFilter(strings.Join(q.Status, ","))
+		if err != nil {
+			errs = append(errs, FieldError{Field: "status", Message: err.Error()})
+		} else {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if len(q.Priority) > 0 {
+		valid := true
+		for _, priority := range q.Priority {
+			if !validPriorities[priority] {
+				errs = append(errs, FieldError{Field: "priority", Message: fmt.Sprintf("invalid priority %q: must be one of low, medium, high", priority)})
+				valid = false
+			}
+		}
+		if valid {
+			conditions = append(conditions, bson.M{"priority": bson.M{"$in": q.Priority}})
+		}
+	}
+
+	switch q.TagMode {
+	case "", "any", "all":
+		if len(q.Tags) > 0 {
+			operator := "$in"
+			if q.TagMode == "all" {
+				operator = "$all"
+			}
+			conditions = append(conditions, bson.M{"tags": bson.M{operator: q.Tags}})
+		}
+	default:
+		errs = append(errs, FieldError{Field: "tagMode", Message: "must be any or all"})
+	}
+
+	for _, tr := range []struct {
+		r     *TimeRange
+		field string
+		name  string
+	}{{q.Due, "dueDate", "due"}, {q.Created, "createdAt", "created"}} {
+		if tr.r == nil {
+			continue
+		}
+		condition, rangeErrs := timeRangeCondition(tr.r, tr.field, tr.name)
+		errs = append(errs, rangeErrs...)
+		if condition != nil {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	} else if q.Limit < 1 || q.Limit > maxPageLimit {
+		errs = append(errs, FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxPageLimit)})
+	}
+	if q.Offset < 0 {
+		errs = append(errs, FieldError{Field: "offset", Message: "must not be negative"})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	filter := notDeleted(bson.M{})
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+	return filter, nil
+}
+
+// QueryItems is a single search endpoint taking every criterion in a JSON
+// QueryRequest body: description text (literal or regex), completion state,
+// statuses, priorities, tags with any/all matching, and due and created date
+// ranges, combined with AND. Results come in creation order, paged with
+// limit (default 50, max 500) and offset. An invalid body gets 400 with the
+// ValidateItem shape, listing every field error.
+func QueryItems(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	var req QueryRequest
+	var filter bson.M
+	var errs []FieldError
+	if err := decodeJSONStrict(r, &req); err != nil {
+		errs = []FieldError{decodeFieldError(err)}
+	} else {
+		filter, errs = req.filter()
+	}
+	if len(errs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationResult{Valid: false, Errors: errs})
+		return
+	}
+
+	log.WithFields(log.Fields{"filter": filter, "limit": req.Limit, "offset": req.Offset}).Info("Query TodoItems")
+
+	total, err := countTodoItems(collection, filter)
+	if err != nil {
+		writeServerError(w, "Failed to query todo items", err)
+		return
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(req.Offset).
+		SetLimit(req.Limit)
+	items, err := findTodoItems(collection, filter, findOptions)
+	if err != nil {
+		writeServerError(w, "Failed to query todo items", err)
+		return
+	}
+	if items == nil {
+		items = []*TodoItemModel{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueryResult{Items: items, Total: total, Limit: req.Limit, Offset: req.Offset})
+}
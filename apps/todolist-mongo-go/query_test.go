@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryRequest_Filter(t *testing.T) {
+	completed := false
+	req := QueryRequest{
+		Completed: &completed,
+		Priority:  []string{"high", "medium"},
+		Tags:      []string{"work", "urgent"},
+		TagMode:   "all",
+		Due:       &TimeRange{After: "2024-01-01T00:00:00Z", Before: "2024-02-01T00:00:00Z"},
+	}
+	filter, errs := req.filter()
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := bson.M{
+		"deletedAt": nil,
+		"$and": bson.A{
+			bson.M{"completed": false},
+			bson.M{"priority": bson.M{"$in": []string{"high", "medium"}}},
+			bson.M{"tags": bson.M{"$all": []string{"work", "urgent"}}},
+			bson.M{"dueDate": bson.M{
+				"$gte": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				"$lte": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+	}
+	if !reflect.DeepEqual(filter, want) {
+		t.Fatalf("got %v, want %v", filter, want)
+	}
+	if req.Limit != defaultPageLimit {
+		t.Fatalf("limit should default to %d, got %d", defaultPageLimit, req.Limit)
+	}
+
+	empty := QueryRequest{}
+	if filter, errs := empty.filter(); errs != nil || !reflect.DeepEqual(filter, bson.M{"deletedAt": nil}) {
+		t.Fatalf("empty query: got %v, %v", filter, errs)
+	}
+}
+
+func TestQueryItems_FieldErrors(t *testing.T) {
+	body := `{
+		"regex": true,
+		"status": ["finished"],
+		"priority": ["urgent"],
+		"tagMode": "some",
+		"due": {"after": "2024-02-01T00:00:00Z", "before": "2024-01-01T00:00:00Z"},
+		"created": {"after": "yesterday"},
+		"limit": 1000,
+		"offset": -1
+	}`
+	rec := httptest.NewRecorder()
+	QueryItems(rec, httptest.NewRequest(http.MethodPost, "/todo/query", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result ValidationResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var fields []string
+	for _, e := range result.Errors {
+		fields = append(fields, e.Field)
+	}
+	sort.Strings(fields)
+	want := []string{"created.after", "due.before", "limit", "offset", "priority", "regex", "status", "tagMode"}
+	if result.Valid || !reflect.DeepEqual(fields, want) {
+		t.Fatalf("got fields %v, want %v", fields, want)
+	}
+
+	rec = httptest.NewRecorder()
+	QueryItems(rec, httptest.NewRequest(http.MethodPost, "/todo/query", strings.NewReader(`{"tags": "work"}`)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"tags"`) {
+		t.Fatalf("expected a tags field error, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
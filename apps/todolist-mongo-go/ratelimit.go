@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// tokenBucket is one client's allowance: up to a minute's worth of requests,
+// refilled continuously
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits requests per minute per key with token buckets. Keys
+// are owners (X-User-ID) or, for anonymous requests or with byOwner off,
+// client IPs. Owners listed in ownerLimits get their own per-minute limit
+// instead of perMinute.
+type rateLimiter struct {
+	perMinute   int
+	ownerLimits map[string]int
+	byOwner     bool
+	now         func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(perMinute int, ownerLimits map[string]int, byOwner bool) *rateLimiter {
+	return &rateLimiter{
+		perMinute:   perMinute,
+		ownerLimits: ownerLimits,
+		byOwner:     byOwner,
+		now:         time.Now,
+		buckets:     map[string]*tokenBucket{},
+	}
+}
+
+// key identifies who r counts against, with the limit that applies to them
+func (l *rateLimiter) key(r *http.Request) (string, int) {
+	if owner := ownerFromRequest(r); l.byOwner && owner != "" {
+		if limit, ok := l.ownerLimits[owner]; ok {
+			return "owner:" + owner, limit
+		}
+		return "owner:" + owner, l.perMinute
+	}
+	return "ip:" + clientIP(r), l.perMinute
+}
+
+// allow takes a token from key's bucket, or reports how long until one is
+// available. A limit of 0 means unlimited.
+func (l *rateLimiter) allow(key string, limit int) (bool, time.Duration) {
+	if limit == 0 {
+		return true, 0
+	}
+	now := l.now()
+	perSecond := float64(limit) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit), last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(limit), bucket.tokens+now.Sub(bucket.last).Seconds()*perSecond)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops, at most once a minute, the buckets idle for a minute or more:
+// they have refilled completely, so forgetting them changes nothing
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// parseOwnerLimits reads RATE_LIMIT_OWNERS entries of the form owner=limit
+func parseOwnerLimits(entries []string) (map[string]int, error) {
+	limits := map[string]int{}
+	for _, entry := range entries {
+		owner, value, ok := strings.Cut(entry, "=")
+		owner = strings.TrimSpace(owner)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || owner == "" || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid entry %q: must be owner=requests per minute", entry)
+		}
+		limits[owner] = n
+	}
+	return limits, nil
+}
+
+// rateLimitMiddleware answers 429 with Retry-After once a client has used
+// its requests for the minute, so one busy owner can't starve the others.
+// The health and readiness probes are exempt. X-User-ID is not
+// authenticated, so this is about fairness between well-behaved tenants
+// rather than protection from a hostile client.
+func rateLimitMiddleware(limiter *rateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProbePath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, limit := limiter.key(r)
+		if ok, wait := limiter.allow(key, limit); !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			log.WithFields(log.Fields{"client": key, "limit": limit}).Warnf("Rejecting %s %s: rate limit exceeded", r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeErrorResponse(w, http.StatusTooManyRequests, "Too Many Requests", fmt.Sprintf("Rate limit of %d requests per minute exceeded, retry in %ds", limit, seconds))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
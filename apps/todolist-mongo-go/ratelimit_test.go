@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(60, nil, true)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := limiter.allow("ip:a", 60); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	ok, wait := limiter.allow("ip:a", 60)
+	if ok || wait != time.Second {
+		t.Fatalf("expected a 1s wait once the bucket is empty, got %v, %v", ok, wait)
+	}
+	if ok, _ := limiter.allow("ip:b", 60); !ok {
+		t.Fatal("other clients must not be affected")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := limiter.allow("ip:a", 60); !ok {
+		t.Fatal("a token should be back after a second")
+	}
+	if ok, _ := limiter.allow("ip:a", 0); !ok {
+		t.Fatal("a limit of 0 is unlimited")
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.allow("ip:c", 60)
+	if _, ok := limiter.buckets["ip:a"]; ok {
+		t.Fatal("idle buckets should be swept")
+	}
+}
+
+func TestRateLimiter_Key(t *testing.T) {
+	limiter := newRateLimiter(10, map[string]int{"vip": 100}, true)
+	req := func(owner string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/todo", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if owner != "" {
+			r.Header.Set(userIDHeader, owner)
+		}
+		return r
+	}
+	tests := []struct {
+		owner string
+		key   string
+		limit int
+	}{
+		{"", "ip:192.0.2.1", 10},
+		{"alice", "owner:alice", 10},
+		{"vip", "owner:vip", 100},
+	}
+	for _, tt := range tests {
+		if key, limit := limiter.key(req(tt.owner)); key != tt.key || limit != tt.limit {
+			t.Errorf("%q: got %s, %d; want %s, %d", tt.owner, key, limit, tt.key, tt.limit)
+		}
+	}
+
+	limiter.byOwner = false
+	if key, _ := limiter.key(req("alice")); key != "ip:192.0.2.1" {
+		t.Errorf("with byOwner off requests should be keyed by IP, got %s", key)
+	}
+}
+
+func TestParseOwnerLimits(t *testing.T) {
+	limits, err := parseOwnerLimits([]string{"alice=600", " bob = 0 "})
+	if err != nil || limits["alice"] != 600 || limits["bob"] != 0 || len(limits) != 2 {
+		t.Fatalf("got %v, %v", limits, err)
+	}
+	for _, entry := range []string{"alice", "=5", "alice=-1", "alice=many"} {
+		if _, err := parseOwnerLimits([]string{entry}); err == nil {
+			t.Errorf("%q: expected an error", entry)
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := rateLimitMiddleware(newRateLimiter(1, nil, true), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	get := func(path, owner string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(userIDHeader, owner)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/todo", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("first request: %d", rec.Code)
+	}
+	rec := get("/todo", "alice")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := get("/todo", "bob"); rec.Code != http.StatusOK {
+		t.Fatalf("another owner should not be limited, got %d", rec.Code)
+	}
+	if rec := get(healthPath, "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("probes are exempt, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxReplaceFindLength caps the text POST /todo/replace-text searches for
+const maxReplaceFindLength = 200
+
+// ReplaceTextRequest is the body of POST /todo/replace-text
+type ReplaceTextRequest struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+}
+
+// replaceConcurrency is how many updates POST /todo/replace-text runs at
+// once. Configured from REPLACE_TEXT_CONCURRENCY in main; 1 updates the
+// items one at a time.
+var replaceConcurrency = 8
+
+// ReplaceTextResult reports how many items contained the text, how many were
+// changed, how many were left alone because the replacement would have
+// blanked the description or the item changed in the meantime, and how many
+// updates failed
+type ReplaceTextResult struct {
+	Matched int `json:"matched"`
+	Changed int `json:"changed"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// replaceJob is one item's pending description change
+type replaceJob struct {
+	id          primitive.ObjectID
+	old         string
+	description string
+}
+
+// runReplaceJobs applies update to jobs with up to workers of them running at
+// once, counting the jobs it changed, skipped (update reported no change) and
+// failed. Once ctx is done no further jobs are started; the ones left over
+// are not counted.
+func runReplaceJobs(ctx context.Context, jobs []replaceJob, workers int, update func(context.Context, replaceJob) (bool, error)) (changed, skipped, failed int) {
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan replaceJob)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				ok, err := update(ctx, job)
+				mu.Lock()
+				switch {
+				case err != nil:
+					failed++
+				case ok:
+					changed++
+				default:
+					skipped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return changed, skipped, failed
+}
+
+// replaceInDescription updates one item's description if it still reads
+// old, returning whether it was changed. The update is bounded by opTimeout
+// and cancelled with ctx.
+func replaceInDescription(ctx context.Context, collection *mongo.Collection, job replaceJob, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+	description := job.description
+	res, err := collection.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": job.id, "description": job.old}),
+		bumpVersion(bson.M{"$set": bson.M{"description": description, "descriptionKey": descriptionKey(description), "updatedAt": now}}))
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ReplaceText replaces every occurrence of find in the descriptions of live
+// items. The match is case-sensitive. Each matching item is updated on its own
+// and only if its description has not changed since it was read, so
+// concurrent edits are not overwritten; up to replaceConcurrency updates run
+// at once. A failed update is counted in the result rather than aborting the
+// others, and a client disconnect cancels the remaining work. Requires
+// confirm=replace.
+func ReplaceText(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	if r.URL.Query().Get("confirm") != "replace" {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Replace requires confirm=replace")
+		return
+	}
+	var req ReplaceTextRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	if req.Find == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "find cannot be empty")
+		return
+	}
+	if len(req.Find) > maxReplaceFindLength {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", fmt.Sprintf("find must be at most %d characters", maxReplaceFindLength))
+		return
+	}
+	if req.Find == req.Replace {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "find and replace must differ")
+		return
+	}
+
+	log.WithFields(log.Fields{"find": req.Find, "replace": req.Replace}).Warn("Replacing text in TodoItem descriptions")
+
+	filter := notDeleted(bson.M{"description": primitive.Regex{Pattern: regexp.QuoteMeta(req.Find)}})
+	findOptions := options.Find().SetProjection(bson.M{"description": 1})
+	items, err := findTodoItems(collection, filter, findOptions)
+	if err != nil {
+		writeServerError(w, "Failed to find todo items", err)
+		return
+	}
+
+	result := ReplaceTextResult{Matched: len(items)}
+	jobs := make([]replaceJob, 0, len(items))
+	for _, item := range items {
+		description := strings.ReplaceAll(item.Description, req.Find, req.Replace)
+		if isBlankDescription(description) || validateDescriptionSize(description) != nil {
+			result.Skipped++
+			continue
+		}
+		jobs = append(jobs, replaceJob{id: item.Id, old: item.Description, description: description})
+	}
+
+	now := time.Now().UTC()
+	changed, skipped, failed := runReplaceJobs(r.Context(), jobs, replaceConcurrency, func(ctx context.Context, job replaceJob) (bool, error) {
+		changed, err := replaceInDescription(ctx, collection, job, now)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to replace text in todo item %s: %v", job.id.Hex(), err)
+		}
+		return changed, err
+	})
+	result.Changed += changed
+	result.Skipped += skipped
+	result.Failed += failed
+	if changed > 0 {
+		// Recorded before the cancellation check: a cancelled run has still
+		// rewritten the items it got to
+		ids := make([]primitive.ObjectID, len(jobs))
+		for i, job := range jobs {
+			ids[i] = job.id
+		}
+		recordBulkAudit(r, collection, auditUpdate, bson.M{"_id": bson.M{"$in": ids}}, now)
+	}
+	if err := r.Context().Err(); err != nil {
+		log.WithFields(log.Fields{"changed": result.Changed, "failed": result.Failed}).Warnf("Replace text cancelled: %v", err)
+		return
+	}
+	if result.Failed > 0 {
+		log.WithFields(log.Fields{"changed": result.Changed, "failed": result.Failed}).Error("Failed to replace text in some todo items")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func replaceJobs(n int) []replaceJob {
+	jobs := make([]replaceJob, n)
+	for i := range jobs {
+		jobs[i] = replaceJob{id: primitive.NewObjectID(), old: "teh", description: "the"}
+	}
+	return jobs
+}
+
+func TestRunReplaceJobs(t *testing.T) {
+	jobs := replaceJobs(30)
+	var running, peak int32
+	changed, skipped, failed := runReplaceJobs(context.Background(), jobs, 4, func(ctx context.Context, job replaceJob) (bool, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		switch job.id {
+		case jobs[0].id:
+			return false, errors.New("boom")
+		case jobs[1].id:
+			return false, nil
+		}
+		return true, nil
+	})
+	if changed != 28 || skipped != 1 || failed != 1 {
+		t.Fatalf("got changed=%d skipped=%d failed=%d, want 28, 1, 1", changed, skipped, failed)
+	}
+	if peak > 4 {
+		t.Fatalf("expected at most 4 concurrent updates, saw %d", peak)
+	}
+}
+
+func TestRunReplaceJobs_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	changed, _, failed := runReplaceJobs(ctx, replaceJobs(100), 2, func(ctx context.Context, job replaceJob) (bool, error) {
+		if atomic.AddInt32(&calls, 1) == 5 {
+			cancel()
+		}
+		return ctx.Err() == nil, ctx.Err()
+	})
+	if calls >= 100 {
+		t.Fatalf("expected cancellation to stop the remaining jobs, ran %d", calls)
+	}
+	if changed+failed != int(calls) {
+		t.Fatalf("every started job should be counted: changed=%d failed=%d calls=%d", changed, failed, calls)
+	}
+}
+
+// benchmarkReplaceJobs runs 200 updates that each take about as long as a
+// round trip to a nearby mongod
+func benchmarkReplaceJobs(b *testing.B, workers int) {
+	jobs := replaceJobs(200)
+	update := func(ctx context.Context, job replaceJob) (bool, error) {
+		time.Sleep(200 * time.Microsecond)
+		return true, nil
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runReplaceJobs(context.Background(), jobs, workers, update)
+	}
+}
+
+func BenchmarkReplaceJobs_Serial(b *testing.B)   { benchmarkReplaceJobs(b, 1) }
+func BenchmarkReplaceJobs_Parallel(b *testing.B) { benchmarkReplaceJobs(b, 8) }
+
+func TestReplaceText(t *testing.T) {
+	collection := setupTestCollection(t)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "fix teh bug", "completed": false},
+		bson.M{"description": "teh", "completed": false},
+		bson.M{"description": "read teh docs", "completed": false},
+		bson.M{"description": "unrelated", "completed": false},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"find": "teh", "replace": "the"}`)
+	ReplaceText(rec, httptest.NewRequest(http.MethodPost, "/todo/replace-text?confirm=replace", body))
+	var result ReplaceTextResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result != (ReplaceTextResult{Matched: 3, Changed: 3}) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"description": primitive.Regex{Pattern: "teh"}})
+	if err != nil || count != 0 {
+		t.Fatalf("expected no descriptions left with teh, got %d (%v)", count, err)
+	}
+}
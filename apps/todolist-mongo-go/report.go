@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reportDateFormat is the date form of report.csv's from, to and date column
+const reportDateFormat = "2006-01-02"
+
+// Report range limits for GET /todo/report.csv
+const (
+	reportDefaultDays = 30
+	reportMaxDays     = 366
+)
+
+// parseReportRange reads the inclusive from and to dates (YYYY-MM-DD, UTC).
+// to defaults to today and from to reportDefaultDays before to.
+func parseReportRange(r *http.Request, now time.Time) (from, to time.Time, err error) {
+	query := r.URL.Query()
+	to = now.UTC().Truncate(24 * time.Hour)
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(reportDateFormat, value); err != nil {
+			return from, to, fmt.Errorf("invalid to value %q: must be a YYYY-MM-DD date", value)
+		}
+	}
+	from = to.AddDate(0, 0, -(reportDefaultDays - 1))
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(reportDateFormat, value); err != nil {
+			return from, to, fmt.Errorf("invalid from value %q: must be a YYYY-MM-DD date", value)
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("to must not be earlier than from")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > reportMaxDays {
+		return from, to, fmt.Errorf("the range covers %d days; at most %d are allowed", days, reportMaxDays)
+	}
+	return from, to, nil
+}
+
+// dailyCounts counts the live items whose field falls on each UTC day in
+// [from, end), keyed by YYYY-MM-DD; days without items are absent
+func dailyCounts(collection *mongo.Collection, field string, from, end time.Time) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{field: bson.M{"$gte": from, "$lt": end}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + field}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var rows []struct {
+		Day   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Day] = row.Count
+	}
+	return counts, nil
+}
+
+// GetReportCSV streams a CSV with one row per UTC day from from to to
+// (inclusive, YYYY-MM-DD; default the last 30 days, at most 366) giving the
+// number of items created and completed that day. Days without activity are
+// included with zeros so the rows can be charted directly.
+func GetReportCSV(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	from, to, err := parseReportRange(r, time.Now())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	log.WithFields(log.Fields{"from": from.Format(reportDateFormat), "to": to.Format(reportDateFormat)}).Info("Get daily report")
+
+	created, err := dailyCounts(collection, "createdAt", from, end)
+	if err != nil {
+		log.Errorf("Failed to aggregate created counts: %v", err)
+		writeServerError(w, "Failed to build report", err)
+		return
+	}
+	completed, err := dailyCounts(collection, "completedAt", from, end)
+	if err != nil {
+		log.Errorf("Failed to aggregate completed counts: %v", err)
+		writeServerError(w, "Failed to build report", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%s-%s.csv"`, from.Format(reportDateFormat), to.Format(reportDateFormat)))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"date", "created", "completed"})
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(reportDateFormat)
+		writer.Write([]string{key, strconv.FormatInt(created[key], 10), strconv.FormatInt(completed[key], 10)})
+	}
+	writer.Flush()
+}
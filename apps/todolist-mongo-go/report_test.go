@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseReportRange(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC)
+	day := func(s string) time.Time {
+		d, _ := time.Parse(reportDateFormat, s)
+		return d
+	}
+
+	from, to, err := parseReportRange(httptest.NewRequest(http.MethodGet, "/todo/report.csv", nil), now)
+	if err != nil || !from.Equal(day("2024-02-10")) || !to.Equal(day("2024-03-10")) {
+		t.Fatalf("default range: got %v..%v, %v", from, to, err)
+	}
+
+	from, to, err = parseReportRange(httptest.NewRequest(http.MethodGet, "/todo/report.csv?from=2024-01-01&to=2024-01-01", nil), now)
+	if err != nil || !from.Equal(day("2024-01-01")) || !to.Equal(from) {
+		t.Fatalf("single day: got %v..%v, %v", from, to, err)
+	}
+
+	for _, query := range []string{
+		"from=2024-01-32",
+		"to=03/10/2024",
+		"from=2024-02-01&to=2024-01-01",
+		"from=2023-01-01&to=2024-01-02",
+	} {
+		if _, _, err := parseReportRange(httptest.NewRequest(http.MethodGet, "/todo/report.csv?"+query, nil), now); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestGetReportCSV(t *testing.T) {
+	collection := setupTestCollection(t)
+	d1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	d3 := time.Date(2024, 1, 3, 23, 0, 0, 0, time.UTC)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "a", "completed": true, "createdAt": d1, "completedAt": d3},
+		bson.M{"description": "b", "completed": false, "createdAt": d1},
+		bson.M{"description": "c", "completed": false, "createdAt": d3.AddDate(0, 0, 1)},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetReportCSV(rec, httptest.NewRequest(http.MethodGet, "/todo/report.csv?from=2024-01-01&to=2024-01-03", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := "date,created,completed\n2024-01-01,2,0\n2024-01-02,0,0\n2024-01-03,0,1\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
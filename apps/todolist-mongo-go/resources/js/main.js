@@ -75,21 +75,21 @@ function addItemToBackend (value) {
 			console.log(data);
                 }
         });
-        return result.Id;
+        return result.id;
 }
 
 function renderTodoList() {
   if (!data.todo.length && !data.completed.length) return;
 
   for (var i = 0; i < data.todo.length; i++) {
-    var value = data.todo[i].Description;
-    var id = data.todo[i].Id;
+    var value = data.todo[i].description;
+    var id = data.todo[i].id;
     addItemToDOM(value, id);
   }
 
   for (var j = 0; j < data.completed.length; j++) {
-    var value = data.completed[j].Description;
-    var id = data.completed[j].Id;
+    var value = data.completed[j].description;
+    var id = data.completed[j].id;
     addItemToDOM(value, id, true);
   }
 }
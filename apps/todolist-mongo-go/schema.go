@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// schemaCollection holds one metadata document per todo collection in the
+// same database, keyed by the collection's name, so every tenant database
+// tracks its own schema version
+const schemaCollection = "schema"
+
+// migration is one step of the schema history. run must be idempotent: it
+// only touches documents that still need it, so re-running it is harmless.
+type migration struct {
+	version int
+	name    string
+	run     func(*mongo.Collection) (int64, error)
+}
+
+// migrations lists every schema migration in order; a new backfill is added
+// at the end with the next version
+var migrations = []migration{
+	{1, "timestamps", backfillTimestamps},
+	{2, "status", backfillStatus},
+	{3, "descriptionKey", backfillDescriptionKeys},
+}
+
+// latestSchemaVersion is the version a fully migrated collection is at
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion describes the schema state of the todo collection.
+// MigratedAt is missing until a migration has been recorded.
+type SchemaVersion struct {
+	Collection string     `json:"collection"`
+	Version    int        `json:"version"`
+	Latest     int        `json:"latest"`
+	Pending    int        `json:"pending"`
+	MigratedAt *time.Time `json:"migratedAt,omitempty"`
+}
+
+// MigrationStep reports one migration run by migrateSchema
+type MigrationStep struct {
+	Version  int    `json:"version"`
+	Name     string `json:"name"`
+	Modified int64  `json:"modified"`
+}
+
+// MigrationSummary reports a migrateSchema run
+type MigrationSummary struct {
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Applied []MigrationStep `json:"applied"`
+}
+
+// schemaVersion reads collection's recorded schema version; a collection
+// that has never been migrated is at version 0
+func schemaVersion(collection *mongo.Collection) (SchemaVersion, error) {
+	info := SchemaVersion{Collection: collection.Name(), Latest: latestSchemaVersion()}
+	ctx, cancel := opContext()
+	defer cancel()
+	var doc struct {
+		Version    int       `bson:"version"`
+		MigratedAt time.Time `bson:"migratedAt"`
+	}
+	err := collection.Database().Collection(schemaCollection).FindOne(ctx, bson.M{"_id": collection.Name()}).Decode(&doc)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return info, err
+	}
+	if err == nil {
+		info.Version = doc.Version
+		info.MigratedAt = &doc.MigratedAt
+	}
+	for _, m := range migrations {
+		if m.version > info.Version {
+			info.Pending++
+		}
+	}
+	return info, nil
+}
+
+// setSchemaVersion records that collection is at version
+func setSchemaVersion(collection *mongo.Collection, version int) error {
+	ctx, cancel := opContext()
+	defer cancel()
+	_, err := collection.Database().Collection(schemaCollection).UpdateOne(ctx,
+		bson.M{"_id": collection.Name()},
+		bson.M{"$set": bson.M{"version": version, "migratedAt": time.Now().UTC()}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// migrateSchema runs the migrations newer than collection's recorded
+// version in order, recording the version after each one so a failure
+// resumes from the step that failed. A collection already at the latest
+// version runs nothing.
+func migrateSchema(collection *mongo.Collection) (MigrationSummary, error) {
+	info, err := schemaVersion(collection)
+	if err != nil {
+		return MigrationSummary{}, err
+	}
+	summary := MigrationSummary{From: info.Version, To: info.Version, Applied: []MigrationStep{}}
+	for _, m := range migrations {
+		if m.version <= summary.To {
+			continue
+		}
+		log.WithFields(log.Fields{"version": m.version, "migration": m.name}).Info("Running migration")
+		modified, err := m.run(collection)
+		if err != nil {
+			return summary, err
+		}
+		if err := setSchemaVersion(collection, m.version); err != nil {
+			return summary, err
+		}
+		summary.To = m.version
+		summary.Applied = append(summary.Applied, MigrationStep{Version: m.version, Name: m.name, Modified: modified})
+	}
+	return summary, nil
+}
+
+// GetSchemaVersion reports the todo collection's schema version, the latest
+// version and how many migrations are pending
+func GetSchemaVersion(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	info, err := schemaVersion(collection)
+	if err != nil {
+		writeServerError(w, "Failed to read the schema version", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// MigrateSchema runs the pending migrations on the todo collection and
+// returns a MigrationSummary listing each one applied with the number of
+// documents it changed. Running it again once up to date applies nothing.
+// Admin: guarded by requireAPIKey.
+func MigrateSchema(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	log.Warn("Migrating the TodoItem schema")
+
+	summary, err := migrateSchema(collection)
+	if err != nil {
+		log.Errorf("Migration failed at version %d: %v", summary.To, err)
+		writeServerError(w, "Migration failed", err)
+		return
+	}
+	log.WithFields(log.Fields{"from": summary.From, "to": summary.To}).Info("Schema migrated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// heartbeatInterval is how often the driver checks each server, and so how
+// quickly it notices a server going away or coming back. Configured from
+// MONGODB_HEARTBEAT_INTERVAL in main; the driver reconnects on its own.
+var heartbeatInterval = 10 * time.Second
+
+// ServerHealth is the driver's view of one server
+type ServerHealth struct {
+	Address string  `json:"address"`
+	Kind    string  `json:"kind"`
+	RTTMs   float64 `json:"rttMs,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// TopologyHealth is the driver's view of the deployment, kept up to date by
+// serverMonitor and reported by /healthz?verbose=true
+type TopologyHealth struct {
+	Kind                 string         `json:"kind"`
+	Writable             bool           `json:"writable"`
+	Servers              []ServerHealth `json:"servers"`
+	ChangedAt            time.Time      `json:"changedAt"`
+	HeartbeatFailures    int64          `json:"heartbeatFailures"`
+	LastHeartbeatError   string         `json:"lastHeartbeatError,omitempty"`
+	SelectionFailures    int64          `json:"selectionFailures"`
+	LastSelectionFailure *time.Time     `json:"lastSelectionFailure,omitempty"`
+}
+
+// topologyState holds the latest TopologyHealth; the failure counters are
+// kept outside the lock because heartbeats update them often
+var topologyState = struct {
+	sync.Mutex
+	health TopologyHealth
+}{health: TopologyHealth{Kind: "Unknown", Servers: []ServerHealth{}}}
+
+var heartbeatFailures int64
+var selectionFailures int64
+
+// describeTopology converts a driver topology description to TopologyHealth.
+// It is writable when a server that accepts writes is known: a standalone,
+// mongos, load balancer or replica set primary.
+func describeTopology(desc description.Topology) TopologyHealth {
+	health := TopologyHealth{Kind: desc.Kind.String(), Servers: []ServerHealth{}}
+	for _, server := range desc.Servers {
+		s := ServerHealth{Address: server.Addr.String(), Kind: server.Kind.String()}
+		if server.AverageRTTSet {
+			s.RTTMs = float64(server.AverageRTT.Microseconds()) / 1000
+		}
+		if server.LastError != nil {
+			s.Error = server.LastError.Error()
+		}
+		switch server.Kind {
+		case description.Standalone, description.Mongos, description.LoadBalancer, description.RSPrimary:
+			health.Writable = true
+		}
+		health.Servers = append(health.Servers, s)
+	}
+	return health
+}
+
+// serverMonitor observes the driver's server discovery and monitoring (SDAM)
+// events. The driver reconnects by itself; this only records and logs what it
+// sees, so outages and failovers show up in the log and in /healthz.
+var serverMonitor = &event.ServerMonitor{
+	TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+		health := describeTopology(e.NewDescription)
+		health.ChangedAt = time.Now().UTC()
+		wasWritable := describeTopology(e.PreviousDescription).Writable
+
+		topologyState.Lock()
+		topologyState.health = health
+		topologyState.Unlock()
+
+		fields := log.Fields{"topology": health.Kind, "servers": len(health.Servers), "writable": health.Writable}
+		switch {
+		case wasWritable && !health.Writable:
+			log.WithFields(fields).Warn("MongoDB has no writable server; the driver will keep reconnecting")
+		case !wasWritable && health.Writable:
+			log.WithFields(fields).Info("MongoDB writable server available")
+		default:
+			log.WithFields(fields).Debug("MongoDB topology changed")
+		}
+	},
+	ServerHeartbeatFailed: func(e *event.ServerHeartbeatFailedEvent) {
+		atomic.AddInt64(&heartbeatFailures, 1)
+		topologyState.Lock()
+		topologyState.health.LastHeartbeatError = e.Failure.Error()
+		topologyState.Unlock()
+		log.WithFields(log.Fields{"connection": e.ConnectionID}).Warnf("MongoDB heartbeat failed: %v", e.Failure)
+	},
+}
+
+// topologyHealth returns a copy of the current TopologyHealth
+func topologyHealth() TopologyHealth {
+	topologyState.Lock()
+	health := topologyState.health
+	health.Servers = append([]ServerHealth{}, health.Servers...)
+	topologyState.Unlock()
+	health.HeartbeatFailures = atomic.LoadInt64(&heartbeatFailures)
+	health.SelectionFailures = atomic.LoadInt64(&selectionFailures)
+	return health
+}
+
+// noteSelectionFailure counts and logs err when the driver gave up finding a
+// server for an operation, which is how an outage reaches the handlers
+func noteSelectionFailure(err error) {
+	var selErr topology.ServerSelectionError
+	if !errors.As(err, &selErr) {
+		return
+	}
+	atomic.AddInt64(&selectionFailures, 1)
+	now := time.Now().UTC()
+	topologyState.Lock()
+	topologyState.health.LastSelectionFailure = &now
+	kind := topologyState.health.Kind
+	topologyState.Unlock()
+	log.WithFields(log.Fields{"topology": kind}).Warnf("MongoDB server selection failed: %v", err)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestDescribeTopology(t *testing.T) {
+	desc := description.Topology{
+		Kind: description.ReplicaSetNoPrimary,
+		Servers: []description.Server{
+			{Addr: "mongo-0:27017", Kind: description.RSSecondary},
+			{Addr: "mongo-1:27017", Kind: description.Unknown, LastError: errors.New("connection refused")},
+		},
+	}
+	health := describeTopology(desc)
+	if health.Kind != "ReplicaSetNoPrimary" || health.Writable {
+		t.Fatalf("got kind %q writable %v, want ReplicaSetNoPrimary and not writable", health.Kind, health.Writable)
+	}
+	if len(health.Servers) != 2 || health.Servers[1].Error != "connection refused" {
+		t.Fatalf("unexpected servers %+v", health.Servers)
+	}
+
+	desc.Kind = description.ReplicaSetWithPrimary
+	desc.Servers[1].Kind = description.RSPrimary
+	if !describeTopology(desc).Writable {
+		t.Fatal("a replica set with a primary should be writable")
+	}
+}
+
+func TestServerMonitor_RecordsTopology(t *testing.T) {
+	defer func(health TopologyHealth) { topologyState.health = health }(topologyState.health)
+
+	serverMonitor.TopologyDescriptionChanged(&event.TopologyDescriptionChangedEvent{
+		NewDescription: description.Topology{
+			Kind:    description.Single,
+			Servers: []description.Server{{Addr: "localhost:27017", Kind: description.Standalone}},
+		},
+	})
+	health := topologyHealth()
+	if health.Kind != "Single" || !health.Writable || health.ChangedAt.IsZero() {
+		t.Fatalf("topology not recorded: %+v", health)
+	}
+}
+
+func TestNoteSelectionFailure(t *testing.T) {
+	before := atomic.LoadInt64(&selectionFailures)
+	noteSelectionFailure(errors.New("some other error"))
+	noteSelectionFailure(context.DeadlineExceeded)
+	if got := atomic.LoadInt64(&selectionFailures); got != before {
+		t.Fatalf("unrelated errors were counted: %d", got-before)
+	}
+
+	err := fmt.Errorf("find: %w", topology.ServerSelectionError{Wrapped: context.DeadlineExceeded})
+	noteSelectionFailure(err)
+	if got := atomic.LoadInt64(&selectionFailures); got != before+1 {
+		t.Fatalf("expected one selection failure, got %d", got-before)
+	}
+	if topologyHealth().LastSelectionFailure == nil {
+		t.Fatal("lastSelectionFailure was not set")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Autocomplete limits
+const (
+	autocompleteMinPrefix    = 2
+	autocompleteDefaultLimit = 10
+	autocompleteMaxLimit     = 25
+)
+
+// Limits on raw regular expressions accepted by the q search parameter
+const (
+	maxSearchPatternLength = 100
+	maxSearchRepeat        = 100
+	maxSearchUnbounded     = 3
+)
+
+// searchPattern returns the Mongo regex pattern for a description search.
+// By default q is escaped and matched as a literal substring. With raw set, q
+// is parsed as a regular expression and rejected when it is too long, uses
+// syntax outside RE2 (backreferences, lookaround) or nests repetition in a
+// way that makes a backtracking engine like MongoDB's PCRE blow up, such as
+// (a+)+ or (a|aa)*, or chains more than maxSearchUnbounded unbounded
+// quantifiers such as .*.*.*.*x. The accepted pattern is sent in its normalized form.
+func searchPattern(q string, raw bool) (string, error) {
+	if !raw {
+		return regexp.QuoteMeta(q), nil
+	}
+	if len([]rune(q)) > maxSearchPatternLength {
+		return "", fmt.Errorf("regex must be at most %d characters", maxSearchPatternLength)
+	}
+	re, err := syntax.Parse(q, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("invalid regex: %v", err)
+	}
+	if err := checkRegexComplexity(re); err != nil {
+		return "", err
+	}
+	return re.String(), nil
+}
+
+// checkRegexComplexity rejects a parsed expression with more than
+// maxSearchUnbounded unbounded quantifiers (*, + or {n,}), which backtrack
+// polynomially against each other even side by side, then checks its nesting
+func checkRegexComplexity(re *syntax.Regexp) error {
+	if countUnbounded(re) > maxSearchUnbounded {
+		return fmt.Errorf("regex must use at most %d unbounded repetitions (*, + or {n,})", maxSearchUnbounded)
+	}
+	return checkRegexNesting(re, false)
+}
+
+// countUnbounded counts the repetitions in re without an upper bound
+func countUnbounded(re *syntax.Regexp) int {
+	n := 0
+	if re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max == -1) {
+		n++
+	}
+	for _, sub := range re.Sub {
+		n += countUnbounded(sub)
+	}
+	return n
+}
+
+// checkRegexNesting walks a parsed expression, rejecting a variable-length
+// repetition or alternation found inside another repetition and counted
+// repetitions above maxSearchRepeat
+func checkRegexNesting(re *syntax.Regexp, inRepeat bool) error {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if re.Op == syntax.OpRepeat && (re.Min > maxSearchRepeat || re.Max > maxSearchRepeat) {
+			return fmt.Errorf("regex repetition counts must be at most %d", maxSearchRepeat)
+		}
+		// A fixed count such as {3} is safe on its own but still multiplies
+		// the backtracking of anything variable inside it
+		if inRepeat && !(re.Op == syntax.OpRepeat && re.Min == re.Max) {
+			return fmt.Errorf("regex must not nest repetitions such as (a+)+")
+		}
+		inRepeat = true
+	case syntax.OpAlternate:
+		if inRepeat {
+			return fmt.Errorf("regex must not repeat alternations such as (a|aa)*")
+		}
+	}
+	for _, sub := range re.Sub {
+		if err := checkRegexNesting(sub, inRepeat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Suggestion is a single autocomplete result
+type Suggestion struct {
+	Id          primitive.ObjectID `bson:"id" json:"id"`
+	Description string             `bson:"_id" json:"description"`
+}
+
+// GetAutocomplete returns distinct descriptions starting with prefix, for a
+// type-ahead box. The match is case-insensitive and the prefix is escaped so
+// it is never interpreted as a regular expression.
+func GetAutocomplete(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	prefix := r.URL.Query().Get("prefix")
+	if len([]rune(prefix)) < autocompleteMinPrefix {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "prefix must be at least "+strconv.Itoa(autocompleteMinPrefix)+" characters")
+		return
+	}
+
+	limit := autocompleteDefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid limit value. Must be a positive integer")
+			return
+		}
+		if n < autocompleteMaxLimit {
+			limit = n
+		} else {
+			limit = autocompleteMaxLimit
+		}
+	}
+
+	log.WithFields(log.Fields{"prefix": prefix, "limit": limit}).Info("Autocomplete TodoItems")
+
+	regex := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix), Options: "i"}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"description": regex})}},
+		{{Key: "$group", Value: bson.M{"_id": "$description", "id": bson.M{"$first": "$_id"}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to query autocomplete suggestions: %v", err)
+		writeServerError(w, "Failed to retrieve suggestions", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	suggestions := []Suggestion{}
+	if err := cur.All(ctx, &suggestions); err != nil {
+		log.Errorf("Failed to decode autocomplete suggestions: %v", err)
+		writeServerError(w, "Failed to retrieve suggestions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSearchPattern_LiteralByDefault(t *testing.T) {
+	for _, q := range []string{"(a+)+$", "milk.*", `\1`, "(?=x)"} {
+		got, err := searchPattern(q, false)
+		if err != nil {
+			t.Fatalf("searchPattern(%q) unexpected error: %v", q, err)
+		}
+		if got != regexp.QuoteMeta(q) {
+			t.Fatalf("searchPattern(%q) = %q, want it escaped", q, got)
+		}
+	}
+}
+
+func TestSearchPattern_RejectsPathologicalRegex(t *testing.T) {
+	for _, q := range []string{
+		"(a+)+$",
+		"(a*)*b",
+		"(a|aa)*c",
+		"(x+x+)+y",
+		"((ab)*)+",
+		"(.*a){20}",
+		"a{1000}",
+		".*.*.*.*.*.*.*.*x",
+		"a+b+c+d{2,}",
+		`(a)\1`,
+		"(?=a)a",
+		strings.Repeat("a", maxSearchPatternLength+1),
+	} {
+		if _, err := searchPattern(q, true); err == nil {
+			t.Errorf("searchPattern(%q, true) = nil error, want rejection", q)
+		}
+	}
+}
+
+func TestSearchPattern_AllowsSimpleRegex(t *testing.T) {
+	for _, q := range []string{"^buy", "milk$", "colou?r", "[a-z]+ing", "(cat|dog) food", "a{3}b*", ".*a.*b.*c"} {
+		if _, err := searchPattern(q, true); err != nil {
+			t.Errorf("searchPattern(%q, true) unexpected error: %v", q, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// seedFile is the JSON file prepopulate loads items from. Configured from
+// SEED_FILE in main; the built-in items are used when it is unset or missing.
+var seedFile string
+
+// defaultSeedItems are inserted when no seed file is available
+var defaultSeedItems = []NewTodoItem{
+	{Description: "prepopulate the db", Completed: true},
+	{Description: "time", Completed: false},
+}
+
+// loadSeedItems reads a JSON array of items in the POST /todo shape from path.
+// A missing file (or empty path) yields defaultSeedItems; a file that exists
+// but can't be parsed, or holds an invalid entry, is an error.
+func loadSeedItems(path string) ([]NewTodoItem, error) {
+	if path == "" {
+		return defaultSeedItems, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Warnf("Seed file %s not found, using built-in items", path)
+		return defaultSeedItems, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []NewTodoItem
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&items); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range items {
+		if err := items[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: item %d: %v", path, i, err)
+		}
+	}
+	return items, nil
+}
+
+// prepopulate adds the seed items to collection. Each is upserted keyed on
+// its description and only written when no live, unowned item with that
+// description exists, so running it again (on every start with PREPOPULATE,
+// or on a collection that already has data) never duplicates or overwrites
+// items. A soft-deleted item or a user's own item with the same text does not
+// stand in for the seed item.
+func prepopulate(collection *mongo.Collection) error {
+	items, err := loadSeedItems(seedFile)
+	if err != nil {
+		log.Errorf("Failed to load seed items: %v", err)
+		return err
+	}
+	if len(items) == 0 {
+		log.Info("Seed file is empty, nothing to prepopulate")
+		return nil
+	}
+
+	log.Infof("Prepopulate the db with %d items", len(items))
+	now := time.Now().UTC()
+	models := make([]mongo.WriteModel, len(items))
+	for i := range items {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(notDeleted(bson.M{"description": items[i].Description, "owner": bson.M{"$exists": false}})).
+			SetUpdate(bson.M{"$setOnInsert": items[i].toModel("", now)}).
+			SetUpsert(true)
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	res, err := collection.BulkWrite(ctx, models)
+	if err != nil {
+		log.Errorf("Failed to prepopulate database: %v", err)
+		return err
+	}
+	log.WithFields(log.Fields{"inserted": res.UpsertedCount, "existing": res.MatchedCount}).Info("Prepopulated the db")
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func writeSeedFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write seed file: %v", err)
+	}
+	return path
+}
+
+func TestLoadSeedItems(t *testing.T) {
+	path := writeSeedFile(t, `[{"description":"water plants","priority":"low","tags":["home"]},{"description":"ship it","completed":true}]`)
+	items, err := loadSeedItems(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0].Description != "water plants" || !items[1].Completed {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+}
+
+func TestLoadSeedItems_FallsBackWhenMissing(t *testing.T) {
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "absent.json")} {
+		items, err := loadSeedItems(path)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", path, err)
+		}
+		if !reflect.DeepEqual(items, defaultSeedItems) {
+			t.Fatalf("%q: expected built-in items, got %+v", path, items)
+		}
+	}
+}
+
+func TestLoadSeedItems_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"description":"not an array"}`,
+		`[{"description":"ok"},{"description":"  "}]`,
+		`[{"description":"bad priority","priority":"urgent"}]`,
+		`[{"descrption":"typo"}]`,
+	} {
+		if _, err := loadSeedItems(writeSeedFile(t, content)); err == nil {
+			t.Errorf("%s: expected an error", content)
+		}
+	}
+}
+
+func TestPrepopulate_Idempotent(t *testing.T) {
+	collection := setupTestCollection(t)
+	defer func(path string) { seedFile = path }(seedFile)
+	seedFile = writeSeedFile(t, `[{"description":"water plants"},{"description":"ship it","completed":true}]`)
+
+	// An existing item with a seed description is kept as it is
+	if _, err := collection.InsertOne(context.TODO(), bson.M{"description": "ship it", "completed": false}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	for run := 1; run <= 2; run++ {
+		if err := prepopulate(collection); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	for description, want := range map[string]int64{"water plants": 1, "ship it": 1} {
+		n, err := collection.CountDocuments(context.TODO(), bson.M{"description": description})
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if n != want {
+			t.Errorf("%q: expected %d item, got %d", description, want, n)
+		}
+	}
+	var existing TodoItemModel
+	if err := collection.FindOne(context.TODO(), bson.M{"description": "ship it"}).Decode(&existing); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if existing.Completed {
+		t.Error("prepopulate overwrote an existing item")
+	}
+}
+
+func TestPrepopulate_IgnoresDeletedAndOwnedItems(t *testing.T) {
+	collection := setupTestCollection(t)
+	defer func(path string) { seedFile = path }(seedFile)
+	seedFile = writeSeedFile(t, `[{"description":"water plants"},{"description":"ship it"}]`)
+
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "water plants", "completed": false, "deletedAt": time.Now().UTC()},
+		bson.M{"description": "ship it", "completed": false, "owner": "alice"},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := prepopulate(collection); err != nil {
+		t.Fatalf("prepopulate: %v", err)
+	}
+
+	for _, description := range []string{"water plants", "ship it"} {
+		n, err := collection.CountDocuments(context.TODO(), notDeleted(bson.M{"description": description, "owner": bson.M{"$exists": false}}))
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("%q: expected the seed item to be added, got %d", description, n)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// eventStream writes Server-Sent Events, flushing after every event so it
+// reaches the client immediately instead of sitting in a buffer
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream checks that w can flush, answering 500 and returning
+// ok=false when it can't, since events would otherwise be held back until
+// the handler returns
+func newEventStream(w http.ResponseWriter) (stream *eventStream, ok bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error", "Streaming not supported")
+		return nil, false
+	}
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+// start sends the SSE headers. X-Accel-Buffering stops nginx and similar
+// proxies from buffering the stream.
+func (s *eventStream) start() {
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+}
+
+// send writes one event, with one data line per line of data. An empty event
+// name sends a default "message" event.
+func (s *eventStream) send(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(s.w, b.String()); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// comment writes an SSE comment, which clients ignore; used as a keep-alive
+func (s *eventStream) comment(text string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// plainWriter is a ResponseWriter that can't flush
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestNewEventStream_RequiresFlusher(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if _, ok := newEventStream(plainWriter{rec}); ok {
+		t.Fatal("expected a writer without Flush to be rejected")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestEventStream_FlushesEachEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream, ok := newEventStream(rec)
+	if !ok {
+		t.Fatal("recorder should support flushing")
+	}
+	stream.start()
+	if rec.Header().Get("X-Accel-Buffering") != "no" || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected headers: %v", rec.Header())
+	}
+
+	rec.Flushed = false
+	if err := stream.send("update", "line one\nline two"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !rec.Flushed {
+		t.Fatal("expected the event to be flushed")
+	}
+	want := "event: update\ndata: line one\ndata: line two\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
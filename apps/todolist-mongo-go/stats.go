@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GetMyStats returns completed/incomplete/overdue counts for the items owned
+// by the caller identified by the X-User-ID header
+func GetMyStats(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	owner := ownerFromRequest(r)
+	if owner == "" {
+		writeErrorResponse(w, http.StatusUnauthorized, "Unauthorized", "Missing "+userIDHeader+" header")
+		return
+	}
+
+	log.WithFields(log.Fields{"owner": owner}).Info("Get owner stats")
+
+	countIf := func(cond interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	incomplete := bson.M{"$eq": bson.A{"$completed", false}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"owner": owner})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"completed":  countIf(bson.M{"$eq": bson.A{"$completed", true}}),
+			"incomplete": countIf(incomplete),
+			"overdue": countIf(bson.M{"$and": bson.A{
+				incomplete,
+				bson.M{"$eq": bson.A{bson.M{"$type": "$dueDate"}, "date"}},
+				bson.M{"$lt": bson.A{"$dueDate", time.Now()}},
+			}}),
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate owner stats: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	stats := struct {
+		Completed  int64 `bson:"completed" json:"completed"`
+		Incomplete int64 `bson:"incomplete" json:"incomplete"`
+		Overdue    int64 `bson:"overdue" json:"overdue"`
+	}{}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&stats); err != nil {
+			log.Errorf("Failed to decode owner stats: %v", err)
+			writeServerError(w, "Failed to retrieve stats", err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetPriorityStats returns the number of items per priority. Items without a
+// priority are counted under "none".
+func GetPriorityStats(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	log.Info("Get priority stats")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$priority", "none"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate priority stats: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	counts := map[string]int64{"none": 0}
+	for priority := range validPriorities {
+		counts[priority] = 0
+	}
+	for cur.Next(ctx) {
+		var bucket struct {
+			Priority string `bson:"_id"`
+			Count    int64  `bson:"count"`
+		}
+		if err := cur.Decode(&bucket); err != nil {
+			log.Errorf("Failed to decode priority stats: %v", err)
+			writeServerError(w, "Failed to retrieve stats", err)
+			return
+		}
+		counts[bucket.Priority] = bucket.Count
+	}
+	if err := cur.Err(); err != nil {
+		log.Errorf("Cursor error: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
+
+// timelineBuckets are the accepted bucket sizes for GetCompletionTimeline
+var timelineBuckets = map[string]bool{"day": true, "week": true}
+
+// TimelinePoint is the number of items completed within one bucket
+type TimelinePoint struct {
+	Bucket time.Time `bson:"_id" json:"bucket"`
+	Count  int64     `bson:"count" json:"count"`
+}
+
+// GetCompletionTimeline returns the number of completed items per day or week,
+// oldest bucket first. Items completed before completedAt was recorded are
+// left out. Weeks start on Monday and buckets are computed in UTC.
+func GetCompletionTimeline(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	if !timelineBuckets[bucket] {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "bucket must be one of day, week")
+		return
+	}
+
+	log.WithFields(log.Fields{"bucket": bucket}).Info("Get completion timeline")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"completed":   true,
+			"completedAt": bson.M{"$type": "date"},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateTrunc": bson.M{
+				"date":        "$completedAt",
+				"unit":        bucket,
+				"startOfWeek": "monday",
+			}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate completion timeline: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	points := []TimelinePoint{}
+	if err := cur.All(ctx, &points); err != nil {
+		log.Errorf("Failed to decode completion timeline: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
+
+// dueBuckets are the accepted bucket sizes for GetDueStats
+var dueBuckets = map[string]bool{"day": true, "week": true, "month": true}
+
+// DueStats counts incomplete items per due date bucket, plus the incomplete
+// items that have no due date
+type DueStats struct {
+	Buckets     []TimelinePoint `json:"buckets"`
+	Unscheduled int64           `json:"unscheduled"`
+}
+
+// GetDueStats returns the number of incomplete items due in each day, week or
+// month, earliest bucket first, for a calendar heatmap. Items without a due
+// date are counted as unscheduled. Weeks start on Monday and buckets are
+// computed in UTC.
+func GetDueStats(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	if !dueBuckets[bucket] {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "bucket must be one of day, week, month")
+		return
+	}
+
+	log.WithFields(log.Fields{"bucket": bucket}).Info("Get due date stats")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(actionable(bson.M{}))}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$type": "$dueDate"}, "date"}},
+				bson.M{"$dateTrunc": bson.M{
+					"date":        "$dueDate",
+					"unit":        bucket,
+					"startOfWeek": "monday",
+				}},
+				nil,
+			}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate due date stats: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Bucket *time.Time `bson:"_id"`
+		Count  int64      `bson:"count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		log.Errorf("Failed to decode due date stats: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+
+	stats := DueStats{Buckets: []TimelinePoint{}}
+	for _, group := range groups {
+		if group.Bucket == nil {
+			stats.Unscheduled = group.Count
+			continue
+		}
+		stats.Buckets = append(stats.Buckets, TimelinePoint{Bucket: *group.Bucket, Count: group.Count})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// CompletionTimeStats is the average time from creation to completion
+type CompletionTimeStats struct {
+	Count     int64   `json:"count"`
+	AverageMs float64 `json:"averageMs"`
+	Average   string  `json:"average"`
+	HasData   bool    `json:"hasData"`
+}
+
+// GetAvgCompletionTime returns the mean duration between createdAt and
+// completedAt over completed items that have both timestamps. With no such
+// items it returns zero and hasData=false.
+func GetAvgCompletionTime(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	log.Info("Get average completion time")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"completed":   true,
+			"createdAt":   bson.M{"$type": "date"},
+			"completedAt": bson.M{"$type": "date"},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       nil,
+			"count":     bson.M{"$sum": 1},
+			"averageMs": bson.M{"$avg": bson.M{"$subtract": bson.A{"$completedAt", "$createdAt"}}},
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate completion time: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var result struct {
+		Count     int64   `bson:"count"`
+		AverageMs float64 `bson:"averageMs"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&result); err != nil {
+			log.Errorf("Failed to decode completion time: %v", err)
+			writeServerError(w, "Failed to retrieve stats", err)
+			return
+		}
+	}
+
+	average := time.Duration(result.AverageMs * float64(time.Millisecond)).Round(time.Second)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CompletionTimeStats{
+		Count:     result.Count,
+		AverageMs: result.AverageMs,
+		Average:   average.String(),
+		HasData:   result.Count > 0,
+	})
+}
+
+// Boundaries for GetLengthHistogram: the defaults and the most a caller may
+// ask for
+var defaultLengthBoundaries = []int{0, 10, 25, 50, 100, 250, 500}
+
+const maxLengthBoundaries = 50
+
+// LengthBucket counts the items whose description is at least Min and less
+// than Max characters long
+type LengthBucket struct {
+	Min   int   `json:"min"`
+	Max   int   `json:"max"`
+	Count int64 `json:"count"`
+}
+
+// LengthHistogram is the distribution of description lengths. Outside counts
+// the items whose length falls outside every bucket.
+type LengthHistogram struct {
+	Buckets []LengthBucket `json:"buckets"`
+	Outside int64          `json:"outside"`
+}
+
+// parseLengthBoundaries reads a comma-separated list of at least two strictly
+// increasing, non-negative lengths
+func parseLengthBoundaries(value string) ([]int, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 || len(parts) > maxLengthBoundaries {
+		return nil, fmt.Errorf("boundaries must list between 2 and %d lengths", maxLengthBoundaries)
+	}
+	boundaries := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid boundary %q: must be a non-negative integer", part)
+		}
+		if i > 0 && n <= boundaries[i-1] {
+			return nil, fmt.Errorf("boundaries must be strictly increasing")
+		}
+		boundaries[i] = n
+	}
+	return boundaries, nil
+}
+
+// GetLengthHistogram buckets items by description length in characters
+// ($strLenCP, so multibyte characters count once) with a $bucket stage. The
+// boundaries parameter sets the bucket edges, e.g. boundaries=0,20,100 for
+// [0,20) and [20,100); the default is 0,10,25,50,100,250,500. Every bucket is
+// listed, empty ones with a zero count, and longer descriptions are counted
+// as outside.
+func GetLengthHistogram(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	boundaries := defaultLengthBoundaries
+	if value := r.URL.Query().Get("boundaries"); value != "" {
+		var err error
+		if boundaries, err = parseLengthBoundaries(value); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+	}
+
+	log.WithFields(log.Fields{"boundaries": boundaries}).Info("Get description length histogram")
+
+	edges := make(bson.A, len(boundaries))
+	for i, boundary := range boundaries {
+		edges[i] = boundary
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$bucket", Value: bson.M{
+			"groupBy":    bson.M{"$strLenCP": bson.M{"$ifNull": bson.A{"$description", ""}}},
+			"boundaries": edges,
+			"default":    "outside",
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}}},
+	}
+
+	ctx, cancel := opContext()
+	defer cancel()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("Failed to aggregate description lengths: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Bucket interface{} `bson:"_id"`
+		Count  int64       `bson:"count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		log.Errorf("Failed to decode description lengths: %v", err)
+		writeServerError(w, "Failed to retrieve stats", err)
+		return
+	}
+
+	histogram := LengthHistogram{Buckets: make([]LengthBucket, len(boundaries)-1)}
+	index := make(map[int64]int, len(boundaries))
+	for i := range histogram.Buckets {
+		histogram.Buckets[i] = LengthBucket{Min: boundaries[i], Max: boundaries[i+1]}
+		index[int64(boundaries[i])] = i
+	}
+	for _, group := range groups {
+		// $bucket labels each bucket with its lower boundary, as stored
+		var lower int64
+		switch v := group.Bucket.(type) {
+		case int32:
+			lower = int64(v)
+		case int64:
+			lower = v
+		default:
+			histogram.Outside += group.Count
+			continue
+		}
+		histogram.Buckets[index[lower]].Count = group.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(histogram)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseLengthBoundaries(t *testing.T) {
+	got, err := parseLengthBoundaries("0, 20,100")
+	if err != nil || !reflect.DeepEqual(got, []int{0, 20, 100}) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for _, value := range []string{"10", "0,0", "20,10", "0,-5", "0,abc", "0,,10"} {
+		if _, err := parseLengthBoundaries(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestGetLengthHistogram(t *testing.T) {
+	collection := setupTestCollection(t)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "abc"},
+		bson.M{"description": "買い物"},
+		bson.M{"description": "0123456789"},
+		bson.M{"description": "this one is much too long for the buckets"},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetLengthHistogram(rec, httptest.NewRequest(http.MethodGet, "/todo/stats/length-histogram?boundaries=0,5,20,30", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var histogram LengthHistogram
+	if err := json.NewDecoder(rec.Body).Decode(&histogram); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := LengthHistogram{Buckets: []LengthBucket{{0, 5, 2}, {5, 20, 1}, {20, 30, 0}}, Outside: 1}
+	if !reflect.DeepEqual(histogram, want) {
+		t.Fatalf("got %+v, want %+v", histogram, want)
+	}
+}
+
+func TestGetLengthHistogram_InvalidBoundaries(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetLengthHistogram(rec, httptest.NewRequest(http.MethodGet, "/todo/stats/length-histogram?boundaries=10,5", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestGetDueStats(t *testing.T) {
+	collection := setupTestCollection(t)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	overdue, dueToday, upcoming := today.AddDate(0, 0, -1), today.Add(12*time.Hour), today.AddDate(0, 0, 3)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "overdue", "completed": false, "dueDate": overdue.Add(9 * time.Hour)},
+		bson.M{"description": "due today", "completed": false, "dueDate": dueToday},
+		bson.M{"description": "upcoming", "completed": false, "dueDate": upcoming.Add(time.Hour)},
+		bson.M{"description": "upcoming too", "completed": false, "dueDate": upcoming.Add(2 * time.Hour)},
+		bson.M{"description": "someday", "completed": false},
+		bson.M{"description": "done", "completed": true, "dueDate": dueToday},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetDueStats(rec, httptest.NewRequest(http.MethodGet, "/todo/stats/due?bucket=day", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats DueStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []TimelinePoint{{Bucket: overdue, Count: 1}, {Bucket: today, Count: 1}, {Bucket: upcoming, Count: 2}}
+	if len(stats.Buckets) != len(want) || stats.Unscheduled != 1 {
+		t.Fatalf("got %+v, want buckets %+v and 1 unscheduled", stats, want)
+	}
+	for i, point := range stats.Buckets {
+		if !point.Bucket.Equal(want[i].Bucket) || point.Count != want[i].Count {
+			t.Errorf("bucket %d: got %+v, want %+v", i, point, want[i])
+		}
+	}
+}
+
+func TestGetDueStats_InvalidBucket(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetDueStats(rec, httptest.NewRequest(http.MethodGet, "/todo/stats/due?bucket=year", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
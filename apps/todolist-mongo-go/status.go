@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Item statuses. Completed is kept in step as status == done.
+const (
+	statusPending    = "pending"
+	statusInProgress = "in_progress"
+	statusDone       = "done"
+	statusAbandoned  = "abandoned"
+)
+
+// validStatuses are the accepted values of the status field
+var validStatuses = map[string]bool{
+	statusPending:    true,
+	statusInProgress: true,
+	statusDone:       true,
+	statusAbandoned:  true,
+}
+
+// statusNames returns the valid statuses in a stable order for messages
+func statusNames() []string {
+	names := make([]string, 0, len(validStatuses))
+	for status := range validStatuses {
+		names = append(names, status)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusFor is the status implied by the completed flag alone
+func statusFor(completed bool) string {
+	if completed {
+		return statusDone
+	}
+	return statusPending
+}
+
+// actionable restricts filter to incomplete items that have not been
+// abandoned, the ones still waiting to be worked on. Abandoned items keep
+// completed false, so that flag alone is not enough.
+func actionable(filter bson.M) bson.M {
+	filter["completed"] = false
+	filter["status"] = bson.M{"$ne": statusAbandoned}
+	return filter
+}
+
+// statusCondition matches items in status. Items stored before the status
+// field existed count as done or pending according to completed.
+func statusCondition(status string) bson.M {
+	switch status {
+	case statusDone, statusPending:
+		return bson.M{"$or": bson.A{
+			bson.M{"status": status},
+			bson.M{"status": bson.M{"$exists": false}, "completed": status == statusDone},
+		}}
+	default:
+		return bson.M{"status": status}
+	}
+}
+
+// parseStatusFilter turns a comma-separated status list into a filter
+// condition matching any of them
+func parseStatusFilter(value string) (bson.M, error) {
+	var conditions bson.A
+	for _, status := range strings.Split(value, ",") {
+		status = strings.TrimSpace(status)
+		if !validStatuses[status] {
+			return nil, fmt.Errorf("invalid status %q: must be one of %s", status, strings.Join(statusNames(), ", "))
+		}
+		conditions = append(conditions, statusCondition(status))
+	}
+	if len(conditions) == 1 {
+		return conditions[0].(bson.M), nil
+	}
+	return bson.M{"$or": conditions}, nil
+}
+
+// SetItemStatus moves an item to the status given as a JSON {"status": ...}
+// body or a status form field and returns the updated item. Completed and
+// completedAt follow: they are set when the status becomes done and cleared
+// for any other status.
+func SetItemStatus(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	id := mux.Vars(r)["id"]
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid ID format")
+		return
+	}
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if isJSONRequest(r) {
+		if err := decodeJSONStrict(r, &body); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+	} else {
+		body.Status = r.FormValue("status")
+	}
+	if !validStatuses[body.Status] {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "status must be one of "+strings.Join(statusNames(), ", "))
+		return
+	}
+
+	log.WithFields(log.Fields{"_id": id, "status": body.Status}).Info("Setting TodoItem status")
+
+	unlock := lockItem(collection, objID.Hex())
+	defer unlock()
+
+	now := time.Now().UTC()
+	update := completionUpdate(bson.M{"status": body.Status, "updatedAt": now}, body.Status == statusDone, now)
+
+	var updated TodoItemModel
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	ctx, cancel := opContext()
+	defer cancel()
+	err = collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": objID}), bumpVersion(update), opts).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeErrorResponse(w, http.StatusNotFound, "Not Found", "Todo item not found")
+			return
+		}
+		log.Errorf("Failed to set todo item status: %v", err)
+		writeServerError(w, "Failed to update todo item", err)
+		return
+	}
+	recordAudit(r, collection, auditUpdate, updated.Id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseStatusFilter(t *testing.T) {
+	got, err := parseStatusFilter("in_progress")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (bson.M{"status": "in_progress"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// done also matches legacy items that only have completed=true
+	got, err = parseStatusFilter("done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := bson.M{"$or": bson.A{
+		bson.M{"status": "done"},
+		bson.M{"status": bson.M{"$exists": false}, "completed": true},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	got, err = parseStatusFilter("abandoned,in_progress")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if or, ok := got["$or"].(bson.A); !ok || len(or) != 2 {
+		t.Fatalf("expected an $or of two statuses, got %v", got)
+	}
+
+	for _, value := range []string{"finished", "done,", ""} {
+		if _, err := parseStatusFilter(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestCompletionUpdate_Status(t *testing.T) {
+	now := time.Now()
+	if set := completionUpdate(bson.M{}, true, now)["$set"].(bson.M); set["status"] != statusDone {
+		t.Fatalf("completing should set status done, got %v", set["status"])
+	}
+	if set := completionUpdate(bson.M{}, false, now)["$set"].(bson.M); set["status"] != statusPending {
+		t.Fatalf("reopening should set status pending, got %v", set["status"])
+	}
+	set := completionUpdate(bson.M{"status": statusAbandoned}, false, now)["$set"].(bson.M)
+	if set["status"] != statusAbandoned {
+		t.Fatalf("an explicit status should be kept, got %v", set["status"])
+	}
+}
+
+func TestSetItemStatus_Invalid(t *testing.T) {
+	for _, body := range []string{`{"status":"finished"}`, `{"status":""}`, `{"state":"done"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/todo/507f1f77bcf86cd799439011/status", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = mux.SetURLVars(req, map[string]string{"id": "507f1f77bcf86cd799439011"})
+		rec := httptest.NewRecorder()
+		SetItemStatus(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestPopItem_SkipsAbandoned(t *testing.T) {
+	collection := setupTestCollection(t)
+	older := time.Now().UTC().Add(-time.Hour)
+	_, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "given up", "completed": false, "status": statusAbandoned, "createdAt": older},
+		bson.M{"description": "still to do", "completed": false, "status": statusPending, "createdAt": older.Add(time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetOldestItem(rec, httptest.NewRequest(http.MethodGet, "/todo/oldest", nil))
+	if !strings.Contains(rec.Body.String(), "still to do") {
+		t.Fatalf("oldest should skip the abandoned item, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		PopItem(rec, httptest.NewRequest(http.MethodPost, "/todo/pop", nil))
+		if rec.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, rec.Code, rec.Body.String())
+		}
+		if want == http.StatusOK && !strings.Contains(rec.Body.String(), "still to do") {
+			t.Fatalf("pop should skip the abandoned item, got %s", rec.Body.String())
+		}
+	}
+}
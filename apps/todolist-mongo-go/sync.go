@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Page sizes for GET /todo/sync
+const (
+	syncDefaultLimit = 100
+	syncMaxLimit     = 500
+)
+
+// SyncCursor is the position after the last item a client has received
+type SyncCursor struct {
+	SinceUpdated time.Time `json:"since_updated"`
+	AfterID      string    `json:"after_id,omitempty"`
+}
+
+// SyncPage is one page of GET /todo/sync
+type SyncPage struct {
+	Items   []*TodoItemModel `json:"items"`
+	Next    SyncCursor       `json:"next"`
+	HasMore bool             `json:"hasMore"`
+}
+
+// SyncItems pages through items in (updatedAt, _id) order starting after the
+// cursor given by since_updated and after_id. Because the position is a key
+// rather than an offset, items changed while a client is paging move behind
+// the cursor and are returned again on a later page instead of shifting the
+// pages and being skipped or repeated. Soft-deleted items are included so
+// deletions sync too. Pass the returned next values back until hasMore is
+// false; keep the last next cursor for the following sync.
+func SyncItems(w http.ResponseWriter, r *http.Request) {
+	collection := todoCollection(r)
+	query := r.URL.Query()
+
+	since, err := time.Parse(time.RFC3339Nano, query.Get("since_updated"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid since_updated value. Must be an RFC3339 timestamp")
+		return
+	}
+	since = since.UTC()
+
+	limit := syncDefaultLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > syncMaxLimit {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid limit value. Must be between 1 and "+strconv.Itoa(syncMaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	filter := bson.M{"updatedAt": bson.M{"$gt": since}}
+	if afterID := query.Get("after_id"); afterID != "" {
+		objID, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid after_id format")
+			return
+		}
+		filter = bson.M{"$or": bson.A{
+			bson.M{"updatedAt": bson.M{"$gt": since}},
+			bson.M{"updatedAt": since, "_id": bson.M{"$gt": objID}},
+		}}
+	}
+
+	log.WithFields(log.Fields{"since_updated": since, "after_id": query.Get("after_id"), "limit": limit}).Info("Sync TodoItems")
+
+	// Fetch one extra item to learn whether another page follows
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit + 1))
+	items, err := findTodoItems(collection, filter, findOptions)
+	if err != nil {
+		writeServerError(w, "Failed to sync todo items", err)
+		return
+	}
+
+	page := SyncPage{Items: items, Next: SyncCursor{SinceUpdated: since, AfterID: query.Get("after_id")}}
+	if len(items) > limit {
+		page.Items, page.HasMore = items[:limit], true
+	}
+	if page.Items == nil {
+		page.Items = []*TodoItemModel{}
+	}
+	if n := len(page.Items); n > 0 {
+		last := page.Items[n-1]
+		page.Next = SyncCursor{SinceUpdated: last.UpdatedAt.UTC(), AfterID: last.Id.Hex()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
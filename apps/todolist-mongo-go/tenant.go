@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// tenantHeader selects a per-tenant database for the request
+const tenantHeader = "X-Tenant"
+
+// validTenant restricts tenant names so they are safe inside a database name
+var validTenant = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+type tenantCollectionKey struct{}
+
+// allowedTenants, when not empty, is the only tenants accepted. Configured
+// from TENANTS in main.
+var allowedTenants map[string]bool
+
+// maxTenants caps how many tenant databases the app will use, since every new
+// name creates one. Configured from MAX_TENANTS in main; 0 means unlimited.
+var maxTenants = 100
+
+var errTenantNotAllowed = errors.New("unknown tenant")
+
+// admitTenant reports whether a tenant not seen before may be used while
+// known tenants are in use
+func admitTenant(tenant string, known int) error {
+	if len(allowedTenants) > 0 && !allowedTenants[tenant] {
+		return errTenantNotAllowed
+	}
+	if maxTenants > 0 && known >= maxTenants {
+		return fmt.Errorf("the limit of %d tenants has been reached", maxTenants)
+	}
+	return nil
+}
+
+// tenantEntry is a tenant's collection handle, whose indexes are built once
+// on first use
+type tenantEntry struct {
+	collection *mongo.Collection
+	indexes    sync.Once
+}
+
+// tenantCollections caches the entry of each tenant seen so far
+var tenantCollections = struct {
+	sync.RWMutex
+	m map[string]*tenantEntry
+}{m: map[string]*tenantEntry{}}
+
+// tenantCollection returns the todo collection in the todolist_<tenant>
+// database. The first request for a tenant is checked with admitTenant and
+// builds the collection's indexes, so TTL, unique descriptions and the query
+// indexes apply to tenants as they do to the default database. The build
+// runs outside the cache lock under an operation timeout, so it only holds
+// up requests for that tenant.
+func tenantCollection(tenant string) (*mongo.Collection, error) {
+	tenantCollections.RLock()
+	entry, ok := tenantCollections.m[tenant]
+	tenantCollections.RUnlock()
+
+	if !ok {
+		tenantCollections.Lock()
+		if entry, ok = tenantCollections.m[tenant]; !ok {
+			if err := admitTenant(tenant, len(tenantCollections.m)); err != nil {
+				tenantCollections.Unlock()
+				return nil, err
+			}
+			log.Infof("Using database todolist_%s for tenant %s", tenant, tenant)
+			entry = &tenantEntry{collection: db.Database("todolist_" + tenant).Collection(tododb.Name())}
+			tenantCollections.m[tenant] = entry
+		}
+		tenantCollections.Unlock()
+	}
+
+	entry.indexes.Do(func() {
+		ctx, cancel := opContext()
+		defer cancel()
+		createIndexes(ctx, entry.collection, false)
+	})
+	return entry.collection, nil
+}
+
+// tenantMiddleware resolves the X-Tenant header to a collection for the
+// request. Requests without the header use the default database.
+func tenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get(tenantHeader)
+		if tenant == "" || tododb == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validTenant.MatchString(tenant) {
+			writeErrorResponse(w, http.StatusBadRequest, "Bad Request", "Invalid "+tenantHeader+" header. Must be 1-32 alphanumeric characters")
+			return
+		}
+		collection, err := tenantCollection(tenant)
+		if err != nil {
+			log.Warnf("Rejecting tenant %s: %v", tenant, err)
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden", fmt.Sprintf("Tenant %s is not available: %v", tenant, err))
+			return
+		}
+		ctx := context.WithValue(r.Context(), tenantCollectionKey{}, collection)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// todoCollection returns the collection a request operates on: the tenant's
+// when X-Tenant was sent, the default one otherwise
+func todoCollection(r *http.Request) *mongo.Collection {
+	if collection, ok := r.Context().Value(tenantCollectionKey{}).(*mongo.Collection); ok {
+		return collection
+	}
+	return tododb
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAdmitTenant(t *testing.T) {
+	defer func(allowed map[string]bool, max int) { allowedTenants, maxTenants = allowed, max }(allowedTenants, maxTenants)
+
+	allowedTenants, maxTenants = nil, 2
+	if err := admitTenant("acme", 1); err != nil {
+		t.Fatalf("expected acme to be admitted, got %v", err)
+	}
+	if err := admitTenant("acme", 2); err == nil {
+		t.Fatal("expected the tenant cap to apply")
+	}
+
+	allowedTenants, maxTenants = map[string]bool{"acme": true}, 0
+	if err := admitTenant("acme", 1000); err != nil {
+		t.Fatalf("expected acme to be admitted without a cap, got %v", err)
+	}
+	if err := admitTenant("other", 0); err != errTenantNotAllowed {
+		t.Fatalf("expected other to be rejected, got %v", err)
+	}
+}
+
+func TestTenantMiddleware_RejectsUnknownTenant(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer func(collection *mongo.Collection, allowed map[string]bool) {
+		tododb, allowedTenants = collection, allowed
+	}(tododb, allowedTenants)
+	// nothing may reach this unconnected collection
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+	allowedTenants = map[string]bool{"acme": true}
+
+	handler := tenantMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("the handler should not run for a rejected tenant")
+	}))
+	for tenant, want := range map[string]int{"other": http.StatusForbidden, "bad-name": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, "/todo", nil)
+		req.Header.Set(tenantHeader, tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", tenant, want, rec.Code)
+		}
+	}
+}
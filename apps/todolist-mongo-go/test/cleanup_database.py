@@ -33,13 +33,13 @@ def cleanup_database(base_url="http://localhost:8000", timeout=30):
             # Delete each incomplete todo
             for todo in incomplete_todos:
                 try:
-                    delete_response = session.delete(f"{base_url}/todo/{todo['Id']}", timeout=timeout)
+                    delete_response = session.delete(f"{base_url}/todo/{todo['id']}", timeout=timeout)
                     if delete_response.status_code in [200, 201]:
-                        print(f"✅ Deleted todo: {todo['Id']}")
+                        print(f"✅ Deleted todo: {todo['id']}")
                     else:
-                        print(f"⚠️  Failed to delete todo {todo['Id']}: {delete_response.status_code}")
+                        print(f"⚠️  Failed to delete todo {todo['id']}: {delete_response.status_code}")
                 except Exception as e:
-                    print(f"⚠️  Error deleting todo {todo['Id']}: {e}")
+                    print(f"⚠️  Error deleting todo {todo['id']}: {e}")
         
         # Get all completed todos
         completed_response = session.get(f"{base_url}/todo-completed", timeout=timeout)
@@ -50,13 +50,13 @@ def cleanup_database(base_url="http://localhost:8000", timeout=30):
             # Delete each completed todo
             for todo in completed_todos:
                 try:
-                    delete_response = session.delete(f"{base_url}/todo/{todo['Id']}", timeout=timeout)
+                    delete_response = session.delete(f"{base_url}/todo/{todo['id']}", timeout=timeout)
                     if delete_response.status_code in [200, 201]:
-                        print(f"✅ Deleted todo: {todo['Id']}")
+                        print(f"✅ Deleted todo: {todo['id']}")
                     else:
-                        print(f"⚠️  Failed to delete todo {todo['Id']}: {delete_response.status_code}")
+                        print(f"⚠️  Failed to delete todo {todo['id']}: {delete_response.status_code}")
                 except Exception as e:
-                    print(f"⚠️  Error deleting todo {todo['Id']}: {e}")
+                    print(f"⚠️  Error deleting todo {todo['id']}: {e}")
         
         print("✅ Database cleanup completed")
         return True
@@ -63,7 +63,7 @@ class TestInvalidInputHandling(unittest.TestCase):
         try:
             todo = self.client.create_todo(long_description, False)
             TestAssertions.assert_todo_item_structure(todo)
-            self.assertEqual(todo["Description"], long_description)
+            self.assertEqual(todo["description"], long_description)
         except requests.RequestException as e:
             # May be rejected due to length limits
             self.assertIn("400", str(e.response.status_code) if hasattr(e, 'response') else str(e))
@@ -74,7 +74,7 @@ class TestInvalidInputHandling(unittest.TestCase):
         
         todo = self.client.create_todo(special_chars, False)
         TestAssertions.assert_todo_item_structure(todo)
-        self.assertEqual(todo["Description"], special_chars)
+        self.assertEqual(todo["description"], special_chars)
     
     def test_create_todo_unicode_characters(self):
         """Test creating todo with unicode characters"""
@@ -82,7 +82,7 @@ class TestInvalidInputHandling(unittest.TestCase):
         
         todo = self.client.create_todo(unicode_description, False)
         TestAssertions.assert_todo_item_structure(todo)
-        self.assertEqual(todo["Description"], unicode_description)
+        self.assertEqual(todo["description"], unicode_description)
     
     def test_update_nonexistent_todo(self):
         """Test updating a todo that doesn't exist"""
@@ -188,7 +188,7 @@ class TestNetworkErrorHandling(unittest.TestCase):
         def delete_worker(worker_id):
             """Worker function to delete the same todo"""
             try:
-                result = self.client.delete_todo(todo["Id"])
+                result = self.client.delete_todo(todo["id"])
                 results.put(f"worker_{worker_id}_success")
             except Exception as e:
                 errors.put(f"worker_{worker_id}: {e}")
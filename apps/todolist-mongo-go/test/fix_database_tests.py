@@ -15,7 +15,7 @@ def fix_database_tests():
     def replace_assertion(match):
         todo_var = match.group(1)
         list_var = match.group(2)
-        return f'any(item["Id"] == {todo_var}["Id"] for item in {list_var})'
+        return f'any(item["id"] == {todo_var}["id"] for item in {list_var})'
     
     # Pattern to match TestAssertions.assert_todo_in_list(todo, list)
     pattern = r'TestAssertions\.assert_todo_in_list\(([^,]+),\s*([^)]+)\)'
@@ -24,15 +24,15 @@ def fix_database_tests():
     # Now we need to replace the assertTrue/assertFalse calls
     # Replace assertTrue(any(...)) with assertTrue(any(...), "message")
     content = re.sub(
-        r'self\.assertTrue\(any\(item\["Id"\] == ([^"]+)\["Id"\] for item in ([^)]+)\)\)',
-        r'self.assertTrue(any(item["Id"] == \1["Id"] for item in \2), f"Todo {\1[\'Id\']} not found in list")',
+        r'self\.assertTrue\(any\(item\["id"\] == ([^"]+)\["id"\] for item in ([^)]+)\)\)',
+        r'self.assertTrue(any(item["id"] == \1["id"] for item in \2), f"Todo {\1[\'Id\']} not found in list")',
         content
     )
     
     # Replace assertFalse(any(...)) with assertFalse(any(...), "message")
     content = re.sub(
-        r'self\.assertFalse\(any\(item\["Id"\] == ([^"]+)\["Id"\] for item in ([^)]+)\)\)',
-        r'self.assertFalse(any(item["Id"] == \1["Id"] for item in \2), f"Todo {\1[\'Id\']} still found in list")',
+        r'self\.assertFalse\(any\(item\["id"\] == ([^"]+)\["id"\] for item in ([^)]+)\)\)',
+        r'self.assertFalse(any(item["id"] == \1["id"] for item in \2), f"Todo {\1[\'Id\']} still found in list")',
         content
     )
     
@@ -44,8 +44,8 @@ class TestTodoCRUDOperations(unittest.TestCase):
         
         # Verify todo structure
         TestAssertions.assert_todo_item_structure(todo)
-        self.assertEqual(todo["Description"], description)
-        self.assertFalse(todo["Completed"])
+        self.assertEqual(todo["description"], description)
+        self.assertFalse(todo["completed"])
         
         # Verify todo appears in incomplete list
         import time
@@ -55,7 +55,7 @@ class TestTodoCRUDOperations(unittest.TestCase):
         # Find the todo by ID in the list
         found_todo = None
         for item in incomplete_todos:
-            if item["Id"] == todo["Id"]:
+            if item["id"] == todo["id"]:
                 found_todo = item
                 break
         
@@ -64,13 +64,13 @@ class TestTodoCRUDOperations(unittest.TestCase):
         if found_todo is None:
             # The todo might not be in the first 50 results due to API limit
             # But we can still verify the todo was created by checking its structure
-            self.assertIsNotNone(todo["Id"], "Todo should have an ID")
-            self.assertEqual(todo["Description"], description)
-            self.assertFalse(todo["Completed"])
-            print(f"Note: Todo {todo['Id']} not in first 50 results due to API limit")
+            self.assertIsNotNone(todo["id"], "Todo should have an ID")
+            self.assertEqual(todo["description"], description)
+            self.assertFalse(todo["completed"])
+            print(f"Note: Todo {todo['id']} not in first 50 results due to API limit")
         else:
-            self.assertEqual(found_todo["Description"], description)
-            self.assertFalse(found_todo["Completed"])
+            self.assertEqual(found_todo["description"], description)
+            self.assertFalse(found_todo["completed"])
     
     def test_update_todo_to_completed(self):
         """Test updating a todo to completed status"""
@@ -79,18 +79,18 @@ class TestTodoCRUDOperations(unittest.TestCase):
         todo = self.client.create_todo(description, False)
         
         # Update to completed
-        update_result = self.client.update_todo(todo["Id"], True)
+        update_result = self.client.update_todo(todo["id"], True)
         self.assertTrue(update_result["updated"])
         
         # Verify it appears in completed list
         completed_todos = self.client.get_completed_todos()
-        found_in_completed = any(item["Id"] == todo["Id"] for item in completed_todos)
-        self.assertTrue(found_in_completed, f"Todo {todo['Id']} not found in completed list")
+        found_in_completed = any(item["id"] == todo["id"] for item in completed_todos)
+        self.assertTrue(found_in_completed, f"Todo {todo['id']} not found in completed list")
         
         # Verify it no longer appears in incomplete list
         incomplete_todos = self.client.get_incomplete_todos()
-        found_in_incomplete = any(item["Id"] == todo["Id"] for item in incomplete_todos)
-        self.assertFalse(found_in_incomplete, f"Todo {todo['Id']} still found in incomplete list")
+        found_in_incomplete = any(item["id"] == todo["id"] for item in incomplete_todos)
+        self.assertFalse(found_in_incomplete, f"Todo {todo['id']} still found in incomplete list")
     
     
     def test_delete_todo_success(self):
@@ -100,18 +100,18 @@ class TestTodoCRUDOperations(unittest.TestCase):
         todo = self.client.create_todo(description, False)
         
         # Delete the todo
-        delete_result = self.client.delete_todo(todo["Id"])
+        delete_result = self.client.delete_todo(todo["id"])
         self.assertTrue(delete_result["deleted"])
         
         # Verify it no longer appears in any list
         incomplete_todos = self.client.get_incomplete_todos() or []
         completed_todos = self.client.get_completed_todos() or []
         
-        found_in_incomplete = any(item["Id"] == todo["Id"] for item in incomplete_todos)
-        found_in_completed = any(item["Id"] == todo["Id"] for item in completed_todos)
+        found_in_incomplete = any(item["id"] == todo["id"] for item in incomplete_todos)
+        found_in_completed = any(item["id"] == todo["id"] for item in completed_todos)
         
-        self.assertFalse(found_in_incomplete, f"Todo {todo['Id']} still found in incomplete list")
-        self.assertFalse(found_in_completed, f"Todo {todo['Id']} still found in completed list")
+        self.assertFalse(found_in_incomplete, f"Todo {todo['id']} still found in incomplete list")
+        self.assertFalse(found_in_completed, f"Todo {todo['id']} still found in completed list")
     
 
 class TestAPIConsistency(unittest.TestCase):
@@ -138,7 +138,7 @@ class TestAPIConsistency(unittest.TestCase):
             todos.append(todo)
         
         # Extract all IDs
-        ids = [todo["Id"] for todo in todos]
+        ids = [todo["id"] for todo in todos]
         
         # Verify all IDs are unique
         self.assertEqual(len(ids), len(set(ids)))
@@ -175,12 +175,12 @@ class TestAPIPerformance(unittest.TestCase):
         
         # Test update operation
         self.metrics.start_timer()
-        self.client.update_todo(todo["Id"], True)
+        self.client.update_todo(todo["id"], True)
         update_time = self.metrics.end_timer()
         
         # Test delete operation
         self.metrics.start_timer()
-        self.client.delete_todo(todo["Id"])
+        self.client.delete_todo(todo["id"])
         delete_time = self.metrics.end_timer()
         
         # Verify reasonable response times (adjust thresholds as needed)
@@ -204,13 +204,13 @@ class TestAPIPerformance(unittest.TestCase):
         # Update all todos and measure time
         start_time = time.time()
         for todo in todos:
-            self.client.update_todo(todo["Id"], True)
+            self.client.update_todo(todo["id"], True)
         update_time = time.time() - start_time
         
         # Delete all todos and measure time
         start_time = time.time()
         for todo in todos:
-            self.client.delete_todo(todo["Id"])
+            self.client.delete_todo(todo["id"])
         delete_time = time.time() - start_time
         
         # Verify reasonable performance (adjust thresholds as needed)
@@ -230,8 +230,8 @@ class TestAPIPerformance(unittest.TestCase):
         for i in range(50):
             description = self.test_data.generate_test_description(f"memory_test_{i}")
             todo = self.client.create_todo(description, False)
-            self.client.update_todo(todo["Id"], True)
-            self.client.delete_todo(todo["Id"])
+            self.client.update_todo(todo["id"], True)
+            self.client.delete_todo(todo["id"])
         
         final_memory = process.memory_info().rss
         memory_increase = final_memory - initial_memory
@@ -265,8 +265,8 @@ class TestAPIReliability(unittest.TestCase):
         """Test log endpoint functionality"""
         # Create some activity
         todo = self.client.create_todo("log_test", False)
-        self.client.update_todo(todo["Id"], True)
-        self.client.delete_todo(todo["Id"])
+        self.client.update_todo(todo["id"], True)
+        self.client.delete_todo(todo["id"])
         
         # Get logs
         logs = self.client.get_logs()
@@ -290,10 +290,10 @@ class TestAPIReliability(unittest.TestCase):
                     todo = self.client.create_todo(description, False)
                     
                     # Update todo
-                    self.client.update_todo(todo["Id"], True)
+                    self.client.update_todo(todo["id"], True)
                     
                     # Delete todo
-                    self.client.delete_todo(todo["Id"])
+                    self.client.delete_todo(todo["id"])
                     
                     results.put(f"worker_{worker_id}_op_{i}_success")
             except Exception as e:
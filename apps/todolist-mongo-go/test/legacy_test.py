@@ -56,7 +56,7 @@ def createToDo(base_url, description, completed):
         return result
     except Exception as e:
         print(f"Error creating task: {e}")
-        return {"Id": None, "Description": description, "Completed": completed}
+        return {"id": None, "description": description, "completed": completed}
 
 def checkToDoLists(base_url, completed):
     """Get todo lists from the application
@@ -109,11 +109,11 @@ def deleteToDoItems(base_url, item):
     """
     client = APIClient(base_url)
     try:
-        result = client.delete_todo(item["Id"])
-        print(f"Deleted item {item['Id']}")
+        result = client.delete_todo(item["id"])
+        print(f"Deleted item {item['id']}")
         return result.get("deleted", False)
     except Exception as e:
-        print(f"Failed to delete item {item['Id']}: {e}")
+        print(f"Failed to delete item {item['id']}: {e}")
         return False
 
 def run_comprehensive_test(base_url):
@@ -140,13 +140,13 @@ def run_comprehensive_test(base_url):
             test3 = createToDo(base_url, f"pytest-3-{date}", False)
             
             # Verify items were created
-            if not test1.get("Id") or not test2.get("Id") or not test3.get("Id"):
+            if not test1.get("id") or not test2.get("id") or not test3.get("id"):
                 print("❌ FAILED: Could not create test items")
                 return False
             
             # Update todo items
-            success1 = updateToDo(base_url, test1["Id"], True)
-            success2 = updateToDo(base_url, test2["Id"], True)
+            success1 = updateToDo(base_url, test1["id"], True)
+            success2 = updateToDo(base_url, test2["id"], True)
             
             if not success1 or not success2:
                 print("❌ FAILED: Could not update test items")
@@ -164,13 +164,13 @@ def run_comprehensive_test(base_url):
             # Test complete or incomplete
             found_completed = False
             for i in completed:
-                if test1["Description"] == i["Description"]:
+                if test1["description"] == i["description"]:
                     found_completed = True
                     break
             
             found_incomplete = False
             for i in incomplete:
-                if test3["Description"] == i["Description"]:
+                if test3["description"] == i["description"]:
                     found_incomplete = True
                     break
             
@@ -195,13 +195,13 @@ def run_comprehensive_test(base_url):
             # Test deleted items
             found_completed_after = False
             for i in completed_after_delete:
-                if test1["Description"] == i["Description"]:
+                if test1["description"] == i["description"]:
                     found_completed_after = True
                     break
             
             found_incomplete_after = False
             for i in incomplete_after_delete:
-                if test3["Description"] == i["Description"]:
+                if test3["description"] == i["description"]:
                     found_incomplete_after = True
                     break
             
@@ -251,8 +251,8 @@ def main():
         test3 = createToDo(base_url, "pytest-1-" + date, False)
         
         # update todo items
-        success = updateToDo(base_url, test1["Id"], True)
-        success = updateToDo(base_url, test2["Id"], True)
+        success = updateToDo(base_url, test1["id"], True)
+        success = updateToDo(base_url, test2["id"], True)
         
         # check todo's
         completed = checkToDoLists(base_url, True)
@@ -265,12 +265,12 @@ def main():
         # test complete or incomplete
         found_completed = False
         for i in completed:
-            if test1["Description"] == i["Description"]:
+            if test1["description"] == i["description"]:
                 found_completed = True
                 
         found_incomplete = False
         for i in incomplete:
-            if test3["Description"] == i["Description"]:
+            if test3["description"] == i["description"]:
                 found_incomplete = True
         
         if found_completed == False or found_incomplete == False:
@@ -289,12 +289,12 @@ def main():
         # Test deleted items
         found_completed = False
         for i in completed:
-            if test1["Description"] == i["Description"]:
+            if test1["description"] == i["description"]:
                 found_completed = True
         
         found_incomplete = False
         for i in incomplete:
-            if test3["Description"] == i["Description"]:
+            if test3["description"] == i["description"]:
                 found_incomplete = True
         
         if found_completed == True or found_incomplete == True:
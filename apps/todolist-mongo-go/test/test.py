@@ -123,15 +123,15 @@ def deleteToDoItems(base_url,item):
     bool
   """
 
-  endpoint = base_url + "/todo/" + str(item["Id"])
+  endpoint = base_url + "/todo/" + str(item["id"])
   # Send a POST request with the data and the endpoint URL
   response = requests.delete(endpoint, verify=False)
   # Check the status code of the response
   if response.status_code == 201 or response.status_code == 200:
-      print("Deleted item " + str(item["Id"]))
+      print("Deleted item " + str(item["id"]))
       return True
   else:
-      print("Failed to delete item " + str(item["Id"]))
+      print("Failed to delete item " + str(item["id"]))
       return False
 
 
@@ -156,8 +156,8 @@ def main():
    test3 = createToDo(base_url, "pytest-1-" + date, False)
 
    # update todo items
-   success = updateToDo(base_url, test1["Id"], True)
-   success = updateToDo(base_url, test2["Id"], True)
+   success = updateToDo(base_url, test1["id"], True)
+   success = updateToDo(base_url, test2["id"], True)
 
    # check todo's
    completed = checkToDoLists(base_url, True)
@@ -170,13 +170,13 @@ def main():
    # test complete or incomplete
    found_completed = False
    for i in completed:
-       if test1["Description"] == i["Description"]:
+       if test1["description"] == i["description"]:
           found_completed = True
           
 
    found_incomplete = False
    for i in incomplete:
-      if test3["Description"] == i["Description"]:
+      if test3["description"] == i["description"]:
          found_incomplete = True
    
    if found_completed == False or found_incomplete == False:
@@ -194,12 +194,12 @@ def main():
    # Test deleted items
    found_completed = False
    for i in completed:
-       if test1["Description"] == i["Description"]:
+       if test1["description"] == i["description"]:
           found_completed = True
 
    found_incomplete = False
    for i in incomplete:
-      if test3["Description"] == i["Description"]:
+      if test3["description"] == i["description"]:
          found_incomplete = True
     
    if found_completed == True or found_incomplete == True:
@@ -59,7 +59,7 @@ class TestDataManager:
             response.raise_for_status()
             item = response.json()
             self.created_items.append(item)
-            logger.info(f"Created test item: {item['Id']}")
+            logger.info(f"Created test item: {item['id']}")
             return item
         except requests.RequestException as e:
             logger.error(f"Failed to create test item: {e}")
@@ -70,12 +70,12 @@ class TestDataManager:
         for item in self.created_items:
             try:
                 self.test_session.delete(
-                    f"{self.base_url}/todo/{item['Id']}",
+                    f"{self.base_url}/todo/{item['id']}",
                     timeout=TestConfig.TIMEOUT
                 )
-                logger.info(f"Cleaned up test item: {item['Id']}")
+                logger.info(f"Cleaned up test item: {item['id']}")
             except requests.RequestException as e:
-                logger.warning(f"Failed to cleanup item {item['Id']}: {e}")
+                logger.warning(f"Failed to cleanup item {item['id']}: {e}")
         
         self.created_items.clear()
     
@@ -180,16 +180,16 @@ class TestAssertions:
     @staticmethod
     def assert_todo_item_structure(item: Dict):
         """Assert that a todo item has the expected structure"""
-        assert "Id" in item, "Todo item missing 'Id' field"
-        assert "Description" in item, "Todo item missing 'Description' field"
-        assert "Completed" in item, "Todo item missing 'Completed' field"
-        assert isinstance(item["Completed"], bool), "Completed field must be boolean"
+        assert "id" in item, "Todo item missing 'id' field"
+        assert "description" in item, "Todo item missing 'description' field"
+        assert "completed" in item, "Todo item missing 'completed' field"
+        assert isinstance(item["completed"], bool), "Completed field must be boolean"
     
     @staticmethod
     def assert_todo_in_list(todo: Dict, todo_list: List[Dict]) -> bool:
         """Assert that a todo item exists in a list"""
         for item in todo_list:
-            if item["Id"] == todo["Id"]:
+            if item["id"] == todo["id"]:
                 return True
         return False
     
@@ -55,15 +55,15 @@ class TestAPIClient(unittest.TestCase):
             mock_response = Mock()
             mock_response.status_code = 201
             mock_response.json.return_value = {
-                "Id": "507f1f77bcf86cd799439011",
-                "Description": "Test todo",
-                "Completed": False
+                "id": "507f1f77bcf86cd799439011",
+                "description": "Test todo",
+                "completed": False
             }
             mock_post.return_value = mock_response
             
             result = self.client.create_todo("Test todo", False)
-            self.assertEqual(result["Description"], "Test todo")
-            self.assertFalse(result["Completed"])
+            self.assertEqual(result["description"], "Test todo")
+            self.assertFalse(result["completed"])
     
     def test_create_todo_http_error(self):
         """Test todo creation with HTTP error"""
@@ -105,16 +105,16 @@ class TestAPIClient(unittest.TestCase):
             mock_response.status_code = 200
             mock_response.json.return_value = [
                 {
-                    "Id": "507f1f77bcf86cd799439011",
-                    "Description": "Completed todo",
-                    "Completed": True
+                    "id": "507f1f77bcf86cd799439011",
+                    "description": "Completed todo",
+                    "completed": True
                 }
             ]
             mock_get.return_value = mock_response
             
             result = self.client.get_completed_todos()
             self.assertEqual(len(result), 1)
-            self.assertTrue(result[0]["Completed"])
+            self.assertTrue(result[0]["completed"])
     
     def test_get_incomplete_todos_success(self):
         """Test successful retrieval of incomplete todos"""
@@ -123,16 +123,16 @@ class TestAPIClient(unittest.TestCase):
             mock_response.status_code = 200
             mock_response.json.return_value = [
                 {
-                    "Id": "507f1f77bcf86cd799439012",
-                    "Description": "Incomplete todo",
-                    "Completed": False
+                    "id": "507f1f77bcf86cd799439012",
+                    "description": "Incomplete todo",
+                    "completed": False
                 }
             ]
             mock_get.return_value = mock_response
             
             result = self.client.get_incomplete_todos()
             self.assertEqual(len(result), 1)
-            self.assertFalse(result[0]["Completed"])
+            self.assertFalse(result[0]["completed"])
 
 class TestTestDataManager(unittest.TestCase):
     """Test the test data manager functionality"""
@@ -165,16 +165,16 @@ class TestTestDataManager(unittest.TestCase):
         mock_response = Mock()
         mock_response.status_code = 201
         mock_response.json.return_value = {
-            "Id": "507f1f77bcf86cd799439011",
-            "Description": "Test item",
-            "Completed": False
+            "id": "507f1f77bcf86cd799439011",
+            "description": "Test item",
+            "completed": False
         }
         mock_post.return_value = mock_response
         
         item = self.test_data.create_test_item("Test item", False)
         
-        self.assertEqual(item["Description"], "Test item")
-        self.assertFalse(item["Completed"])
+        self.assertEqual(item["description"], "Test item")
+        self.assertFalse(item["completed"])
         self.assertEqual(len(self.test_data.created_items), 1)
     
     @patch('requests.Session.post')
@@ -183,15 +183,15 @@ class TestTestDataManager(unittest.TestCase):
         mock_response = Mock()
         mock_response.status_code = 201
         mock_response.json.return_value = {
-            "Id": "507f1f77bcf86cd799439011",
-            "Description": "test_20231201_120000_abcd",
-            "Completed": False
+            "id": "507f1f77bcf86cd799439011",
+            "description": "test_20231201_120000_abcd",
+            "completed": False
         }
         mock_post.return_value = mock_response
         
         item = self.test_data.create_test_item()
         
-        self.assertTrue(item["Description"].startswith("test_"))
+        self.assertTrue(item["description"].startswith("test_"))
         self.assertEqual(len(self.test_data.created_items), 1)
     
     @patch('requests.Session.post')
@@ -207,8 +207,8 @@ class TestTestDataManager(unittest.TestCase):
         """Test cleanup of all test items"""
         # Add some mock items
         self.test_data.created_items = [
-            {"Id": "507f1f77bcf86cd799439011"},
-            {"Id": "507f1f77bcf86cd799439012"}
+            {"id": "507f1f77bcf86cd799439011"},
+            {"id": "507f1f77bcf86cd799439012"}
         ]
         
         mock_response = Mock()
@@ -250,9 +250,9 @@ class TestTestAssertions(unittest.TestCase):
     def test_assert_todo_item_structure_valid(self):
         """Test todo item structure assertion with valid item"""
         valid_item = {
-            "Id": "507f1f77bcf86cd799439011",
-            "Description": "Test todo",
-            "Completed": True
+            "id": "507f1f77bcf86cd799439011",
+            "description": "Test todo",
+            "completed": True
         }
         
         # Should not raise an exception
@@ -261,8 +261,8 @@ class TestTestAssertions(unittest.TestCase):
     def test_assert_todo_item_structure_missing_id(self):
         """Test todo item structure assertion with missing ID"""
         invalid_item = {
-            "Description": "Test todo",
-            "Completed": True
+            "description": "Test todo",
+            "completed": True
         }
         
         with self.assertRaises(AssertionError):
@@ -271,9 +271,9 @@ class TestTestAssertions(unittest.TestCase):
     def test_assert_todo_item_structure_wrong_completed_type(self):
         """Test todo item structure assertion with wrong completed type"""
         invalid_item = {
-            "Id": "507f1f77bcf86cd799439011",
-            "Description": "Test todo",
-            "Completed": "true"  # Should be boolean
+            "id": "507f1f77bcf86cd799439011",
+            "description": "Test todo",
+            "completed": "true"  # Should be boolean
         }
         
         with self.assertRaises(AssertionError):
@@ -281,10 +281,10 @@ class TestTestAssertions(unittest.TestCase):
     
     def test_assert_todo_in_list_present(self):
         """Test todo in list assertion when todo is present"""
-        todo = {"Id": "507f1f77bcf86cd799439011"}
+        todo = {"id": "507f1f77bcf86cd799439011"}
         todo_list = [
-            {"Id": "507f1f77bcf86cd799439011", "Description": "Test 1"},
-            {"Id": "507f1f77bcf86cd799439012", "Description": "Test 2"}
+            {"id": "507f1f77bcf86cd799439011", "description": "Test 1"},
+            {"id": "507f1f77bcf86cd799439012", "description": "Test 2"}
         ]
         
         result = TestAssertions.assert_todo_in_list(todo, todo_list)
@@ -292,10 +292,10 @@ class TestTestAssertions(unittest.TestCase):
     
     def test_assert_todo_in_list_absent(self):
         """Test todo in list assertion when todo is absent"""
-        todo = {"Id": "507f1f77bcf86cd799439013"}
+        todo = {"id": "507f1f77bcf86cd799439013"}
         todo_list = [
-            {"Id": "507f1f77bcf86cd799439011", "Description": "Test 1"},
-            {"Id": "507f1f77bcf86cd799439012", "Description": "Test 2"}
+            {"id": "507f1f77bcf86cd799439011", "description": "Test 1"},
+            {"id": "507f1f77bcf86cd799439012", "description": "Test 2"}
         ]
         
         result = TestAssertions.assert_todo_in_list(todo, todo_list)
@@ -303,10 +303,10 @@ class TestTestAssertions(unittest.TestCase):
     
     def test_assert_todo_not_in_list_present(self):
         """Test todo not in list assertion when todo is present"""
-        todo = {"Id": "507f1f77bcf86cd799439011"}
+        todo = {"id": "507f1f77bcf86cd799439011"}
         todo_list = [
-            {"Id": "507f1f77bcf86cd799439011", "Description": "Test 1"},
-            {"Id": "507f1f77bcf86cd799439012", "Description": "Test 2"}
+            {"id": "507f1f77bcf86cd799439011", "description": "Test 1"},
+            {"id": "507f1f77bcf86cd799439012", "description": "Test 2"}
         ]
         
         result = TestAssertions.assert_todo_not_in_list(todo, todo_list)
@@ -314,10 +314,10 @@ class TestTestAssertions(unittest.TestCase):
     
     def test_assert_todo_not_in_list_absent(self):
         """Test todo not in list assertion when todo is absent"""
-        todo = {"Id": "507f1f77bcf86cd799439013"}
+        todo = {"id": "507f1f77bcf86cd799439013"}
         todo_list = [
-            {"Id": "507f1f77bcf86cd799439011", "Description": "Test 1"},
-            {"Id": "507f1f77bcf86cd799439012", "Description": "Test 2"}
+            {"id": "507f1f77bcf86cd799439011", "description": "Test 1"},
+            {"id": "507f1f77bcf86cd799439012", "description": "Test 2"}
         ]
         
         result = TestAssertions.assert_todo_not_in_list(todo, todo_list)
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsVersions maps the accepted TLS_MIN_VERSION values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSMinVersion resolves a TLS_MIN_VERSION value; anything other than
+// 1.2 or 1.3 is an error, so older protocol versions cannot be enabled
+func parseTLSMinVersion(value string) (uint16, error) {
+	version, ok := tlsVersions[value]
+	if !ok {
+		return 0, fmt.Errorf("must be 1.2 or 1.3")
+	}
+	return version, nil
+}
+
+// serverTLSConfig is the TLS configuration of the HTTPS server. Only the
+// minimum version is set; the cipher suites are crypto/tls's defaults, which
+// are already restricted to secure ones.
+func serverTLSConfig(minVersion uint16) *tls.Config {
+	return &tls.Config{MinVersion: minVersion}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTLSMinVersion(t *testing.T) {
+	tests := map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+	for value, want := range tests {
+		if got, err := parseTLSMinVersion(value); err != nil || got != want {
+			t.Errorf("%q: got %x, %v; want %x", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "1.0", "1.1", "TLS1.3", "1.4"} {
+		if _, err := parseTLSMinVersion(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestServerTLSConfig_RejectsOlderClients(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = serverTLSConfig(tls.VersionTLS13)
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(maxVersion uint16) error {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.MaxVersion = maxVersion
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(tls.VersionTLS12); err == nil {
+		t.Fatal("a TLS 1.2 client should be rejected")
+	}
+	if err := get(tls.VersionTLS13); err != nil {
+		t.Fatalf("a TLS 1.3 client should connect: %v", err)
+	}
+}
@@ -0,0 +1,854 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// setupTestCollection points tododb at a scratch collection on the MongoDB
+// given by TEST_MONGO_URI, skipping the test when it is not set
+func setupTestCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI not set, skipping MongoDB integration test")
+	}
+
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	collection := client.Database("todolist_test").Collection(t.Name())
+	collection.Drop(context.TODO())
+
+	prev := tododb
+	tododb = collection
+	t.Cleanup(func() {
+		tododb = prev
+		collection.Drop(context.TODO())
+		client.Disconnect(context.TODO())
+	})
+	return collection
+}
+
+func TestGetTodoItems_MalformedDocument(t *testing.T) {
+	collection := setupTestCollection(t)
+
+	_, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "good", "completed": false},
+		bson.M{"description": "legacy", "completed": false, "createdAt": "not a date"},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	prev := strictDecode
+	defer func() { strictDecode = prev }()
+
+	strictDecode = false
+	items, err := GetTodoItems(false)
+	if err != nil {
+		t.Fatalf("lenient decode returned error: %v", err)
+	}
+	if len(items) != 1 || items[0].Description != "good" {
+		t.Fatalf("expected only the good item, got %+v", items)
+	}
+
+	strictDecode = true
+	if _, err := GetTodoItems(false); err == nil {
+		t.Fatal("strict decode should fail on the malformed document")
+	}
+}
+
+func TestTodoItemModel_JSONShape(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("507f1f77bcf86cd799439011")
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := TodoItemModel{
+		Id:          id,
+		Description: "buy milk",
+		Completed:   true,
+		Priority:    "high",
+		Tags:        []string{"home"},
+		DueDate:     &created,
+		Owner:       "alice",
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+
+	got, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"id":"507f1f77bcf86cd799439011","description":"buy milk","completed":true,` +
+		`"priority":"high","tags":["home"],"dueDate":"2024-01-02T03:04:05Z","owner":"alice",` +
+		`"createdAt":"2024-01-02T03:04:05Z","updatedAt":"2024-01-02T03:04:05Z"}`
+	if string(got) != want {
+		t.Fatalf("unexpected JSON\n got: %s\nwant: %s", got, want)
+	}
+
+	got, err = json.Marshal(TodoItemModel{Id: id, Description: "minimal"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want = `{"id":"507f1f77bcf86cd799439011","description":"minimal","completed":false,` +
+		`"createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}`
+	if string(got) != want {
+		t.Fatalf("unexpected JSON for minimal item\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestCreateItem_RejectsUnknownJSONField(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/todo", strings.NewReader(`{"descrption":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	CreateItem(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.Contains(body.Message, `"descrption"`) {
+		t.Fatalf("expected message to name the field, got %q", body.Message)
+	}
+}
+
+func TestCreateItem_CreatedWithLocation(t *testing.T) {
+	setupTestCollection(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/todo", strings.NewReader(`{"description":"buy milk"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	CreateItem(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var item TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if item.Id.IsZero() || item.Description != "buy milk" {
+		t.Fatalf("unexpected body: %+v", item)
+	}
+	if got, want := rec.Header().Get("Location"), "/todo/"+item.Id.Hex(); got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestCreateItem_CaseInsensitiveDuplicate(t *testing.T) {
+	collection := setupTestCollection(t)
+	prev := uniqueDescriptions
+	uniqueDescriptions = true
+	defer func() { uniqueDescriptions = prev }()
+	ensureIndexes(collection, false)
+
+	create := func(description string) int {
+		req := httptest.NewRequest(http.MethodPost, "/todo", strings.NewReader(`{"description":"`+description+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		CreateItem(rec, req)
+		return rec.Code
+	}
+
+	if code := create("Buy Milk"); code != http.StatusCreated {
+		t.Fatalf("first create: expected 201, got %d", code)
+	}
+	for _, description := range []string{"buy milk", "BUY MILK", "Buy Milk"} {
+		if code := create(description); code != http.StatusConflict {
+			t.Errorf("create %q: expected 409, got %d", description, code)
+		}
+	}
+
+	// A soft-deleted item no longer blocks its description
+	now := time.Now().UTC()
+	if _, err := collection.UpdateMany(context.TODO(), bson.M{}, bson.M{"$set": bson.M{"deletedAt": now}}); err != nil {
+		t.Fatalf("soft delete: %v", err)
+	}
+	if code := create("buy milk"); code != http.StatusCreated {
+		t.Fatalf("create after delete: expected 201, got %d", code)
+	}
+}
+
+func TestWriteServerError_Verbosity(t *testing.T) {
+	prev := verboseErrors
+	defer func() { verboseErrors = prev }()
+	cause := errors.New("connection refused")
+
+	for _, verbose := range []bool{false, true} {
+		verboseErrors = verbose
+		rec := httptest.NewRecorder()
+		writeServerError(rec, "Failed to retrieve todo items", cause)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d", rec.Code)
+		}
+		var body ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if got := strings.Contains(body.Message, cause.Error()); got != verbose {
+			t.Fatalf("verbose=%v: message %q", verbose, body.Message)
+		}
+	}
+}
+
+func TestBuildPatchSet_SetClearLeaveAlone(t *testing.T) {
+	var body map[string]json.RawMessage
+	err := json.Unmarshal([]byte(`{"priority":"high","dueDate":null,"tags":null}`), &body)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	set, unset, err := buildPatchSet(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(set) != 1 || set["priority"] != "high" {
+		t.Fatalf("expected only priority to be set, got %v", set)
+	}
+	if len(unset) != 2 || unset["dueDate"] != "" || unset["tags"] != "" {
+		t.Fatalf("expected dueDate and tags to be cleared, got %v", unset)
+	}
+	for _, key := range []string{"description", "completed"} {
+		if _, ok := set[key]; ok {
+			t.Fatalf("omitted key %q should be left alone", key)
+		}
+		if _, ok := unset[key]; ok {
+			t.Fatalf("omitted key %q should be left alone", key)
+		}
+	}
+}
+
+func TestBuildPatchSet_NullOnRequiredField(t *testing.T) {
+	for _, input := range []string{`{"description":null}`, `{"completed":null}`} {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(input), &body); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, _, err := buildPatchSet(body); err == nil {
+			t.Fatalf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestValidateDescriptionSize_CountsRunes(t *testing.T) {
+	defer func(length, bytes int) { maxDescriptionLength, maxDescriptionBytes = length, bytes }(maxDescriptionLength, maxDescriptionBytes)
+	maxDescriptionLength, maxDescriptionBytes = 5, 0
+
+	tests := []struct {
+		description string
+		ok          bool
+	}{
+		{"abcde", true},
+		{"abcdef", false},
+		{"買い物リス", true},           // 5 runes, 15 bytes
+		{"買い物リスト", false},         // 6 runes
+		{"🎉🎉🎉🎉🎉", true},           // 5 runes, 20 bytes
+		{"e\u0301e\u0301e", true}, // combining accents count as their own runes
+		{"e\u0301e\u0301e\u0301", false},
+	}
+	for _, tt := range tests {
+		if err := validateDescriptionSize(tt.description); (err == nil) != tt.ok {
+			t.Errorf("%q (%d bytes): ok=%v, got error %v", tt.description, len(tt.description), tt.ok, err)
+		}
+	}
+}
+
+func TestValidateDescriptionSize_ByteCap(t *testing.T) {
+	defer func(length, bytes int) { maxDescriptionLength, maxDescriptionBytes = length, bytes }(maxDescriptionLength, maxDescriptionBytes)
+	maxDescriptionLength, maxDescriptionBytes = 100, 12
+
+	tests := []struct {
+		description string
+		ok          bool
+	}{
+		{"twelve bytes", true},
+		{"thirteen byte", false},
+		{"買い物リ", true},   // 4 runes, 12 bytes
+		{"買い物リス", false}, // 5 runes, 15 bytes: within the length, over the byte cap
+		{"🎉🎉🎉", true},    // 12 bytes
+		{"🎉🎉🎉🎉", false},
+	}
+	for _, tt := range tests {
+		if err := validateDescriptionSize(tt.description); (err == nil) != tt.ok {
+			t.Errorf("%q (%d bytes): ok=%v, got error %v", tt.description, len(tt.description), tt.ok, err)
+		}
+	}
+}
+
+func TestValidateTags_CountsRunes(t *testing.T) {
+	defer func(length int) { maxTagLength = length }(maxTagLength)
+	maxTagLength = 4
+
+	tests := []struct {
+		tag string
+		ok  bool
+	}{
+		{"home", true},
+		{"homes", false},
+		{"買い物", true},  // 3 runes, 9 bytes
+		{"🏠🏠🏠🏠", true}, // 4 runes, 16 bytes
+		{"🏠🏠🏠🏠🏠", false},
+	}
+	for _, tt := range tests {
+		if err := validateTags([]string{tt.tag}); (err == nil) != tt.ok {
+			t.Errorf("%q (%d bytes): ok=%v, got error %v", tt.tag, len(tt.tag), tt.ok, err)
+		}
+	}
+}
+
+func TestBuildPatchSet_DescriptionTooLong(t *testing.T) {
+	defer func(length int) { maxDescriptionLength = length }(maxDescriptionLength)
+	maxDescriptionLength = 3
+
+	for input, ok := range map[string]bool{`{"description":"日本語"}`: true, `{"description":"日本語です"}`: false} {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(input), &body); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, _, err := buildPatchSet(body); (err == nil) != ok {
+			t.Errorf("%s: ok=%v, got error %v", input, ok, err)
+		}
+	}
+}
+
+func TestMongoHostCandidates(t *testing.T) {
+	defer func(hosts []string) { mongoHosts = hosts }(mongoHosts)
+
+	mongoHosts = nil
+	if got, want := mongoHostCandidates(), []string{localMongoHost}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	mongoHosts = []string{"mongo-1.mongo:27017", "mongo-0.mongo", "mongo-1.mongo:27017", localMongoHost}
+	want := []string{"mongo-1.mongo:27017", "mongo-0.mongo", localMongoHost}
+	if got := mongoHostCandidates(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(mongoHosts) != 4 {
+		t.Fatalf("mongoHosts was modified: %v", mongoHosts)
+	}
+}
+
+func TestValidateMongoHost(t *testing.T) {
+	for _, host := range []string{"mongo-0.mongo", "mongo-0.mongo:27017", localMongoHost, "mongo-0,mongo-1:27018", "mongo/?replicaSet=rs0"} {
+		if err := validateMongoHost(host); err != nil {
+			t.Errorf("%q: unexpected error: %v", host, err)
+		}
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"mongodb://mongo-0", "not a URI"},
+		{"", "at least 1 host"},
+		{"mongo-0:99999", "port must be in the range"},
+		{"mongo-0:port", "port"},
+		{"mongo-0?replicaSet=rs0", "must have a / before the query"},
+		{"mongo-0/?w=-2", "invalid value for w"},
+		{"mongo-0/?replcaSet=rs0", "unknown options replcaset"},
+	}
+	for _, tt := range tests {
+		err := validateMongoHost(tt.host)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected an error containing %q, got %v", tt.host, tt.want, err)
+			continue
+		}
+		if strings.Contains(err.Error(), "changeme") {
+			t.Errorf("%q: error leaks the credentials: %v", tt.host, err)
+		}
+	}
+}
+
+func TestIncludeDeleted_RequiresAPIKey(t *testing.T) {
+	defer func(key string) { apiKey = key }(apiKey)
+
+	tests := []struct {
+		key    string
+		query  string
+		header string
+		status int
+	}{
+		{"", "include_deleted=true", "", http.StatusForbidden},
+		{"secret", "include_deleted=true", "", http.StatusUnauthorized},
+		{"secret", "include_deleted=true", "wrong", http.StatusUnauthorized},
+		{"secret", "include_deleted=maybe", "secret", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		apiKey = tt.key
+		for _, handler := range []http.HandlerFunc{GetAllItems, GetItem} {
+			req := httptest.NewRequest(http.MethodGet, "/todo?"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": primitive.NewObjectID().Hex()})
+			if tt.header != "" {
+				req.Header.Set(apiKeyHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("key %q, %s, header %q: expected %d, got %d", tt.key, tt.query, tt.header, tt.status, rec.Code)
+			}
+		}
+	}
+}
+
+func TestGetItem_IncludeDeleted(t *testing.T) {
+	collection := setupTestCollection(t)
+	defer func(key string) { apiKey = key }(apiKey)
+	apiKey = "secret"
+
+	now := time.Now().UTC()
+	res, err := collection.InsertOne(context.TODO(), bson.M{"description": "gone", "completed": false, "deletedAt": now})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	id := res.InsertedID.(primitive.ObjectID).Hex()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/todo/"+id+query, nil)
+		req.Header.Set(apiKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		GetItem(rec, mux.SetURLVars(req, map[string]string{"id": id}))
+		return rec
+	}
+	if rec := get(""); rec.Code != http.StatusNotFound {
+		t.Fatalf("deleted items should be hidden by default, got %d", rec.Code)
+	}
+	rec := get("?include_deleted=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var item TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !item.Deleted || item.DeletedAt == nil {
+		t.Fatalf("expected the item to be marked deleted, got %+v", item)
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	tests := []struct {
+		header, query string
+		version       int64
+		ok, err       bool
+	}{
+		{"", "", 0, false, false},
+		{"*", "", 0, false, false},
+		{`"3"`, "", 3, true, false},
+		{`W/"3"`, "", 3, true, false},
+		{"0", "", 0, true, false},
+		{"", "?expected_version=7", 7, true, false},
+		{`"2"`, "?expected_version=7", 2, true, false},
+		{`"abc"`, "", 0, false, true},
+		{`"1", "2"`, "", 0, false, true},
+		{"", "?expected_version=-1", 0, false, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/todo/x"+tt.query, nil)
+		if tt.header != "" {
+			req.Header.Set("If-Match", tt.header)
+		}
+		version, ok, err := expectedVersion(req)
+		if version != tt.version || ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("If-Match %q, query %q: got %d, %v, %v", tt.header, tt.query, version, ok, err)
+		}
+	}
+}
+
+func TestDeleteItem_IfMatch(t *testing.T) {
+	collection := setupTestCollection(t)
+	res, err := collection.InsertOne(context.TODO(), bson.M{"description": "versioned", "completed": false})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	id := res.InsertedID.(primitive.ObjectID).Hex()
+	call := func(handler http.HandlerFunc, method, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/todo/"+id, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, mux.SetURLVars(req, map[string]string{"id": id}))
+		return rec
+	}
+
+	etag := call(GetItem, http.MethodGet, "").Header().Get("ETag")
+	if etag != `"0"` {
+		t.Fatalf("expected ETag \"0\" before any change, got %q", etag)
+	}
+	patch := httptest.NewRequest(http.MethodPatch, "/todo/"+id, strings.NewReader(`{"description":"changed"}`))
+	rec := httptest.NewRecorder()
+	PatchItem(rec, mux.SetURLVars(patch, map[string]string{"id": id}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := call(DeleteItem, http.MethodDelete, etag); rec.Code != http.StatusConflict {
+		t.Fatalf("stale ETag should get 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	etag = call(GetItem, http.MethodGet, "").Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\" after one change, got %q", etag)
+	}
+	if rec := call(DeleteItem, http.MethodDelete, etag); rec.Code != http.StatusOK {
+		t.Fatalf("current ETag should delete, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(DeleteItem, http.MethodDelete, etag); rec.Code != http.StatusNotFound {
+		t.Fatalf("deleted item should get 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestItemLive(t *testing.T) {
+	collection := setupTestCollection(t)
+	live, err := collection.InsertOne(context.TODO(), bson.M{"description": "live", "completed": false})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	gone, err := collection.InsertOne(context.TODO(), bson.M{"description": "gone", "completed": false, "deletedAt": time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		id   primitive.ObjectID
+		want bool
+	}{
+		{"live", live.InsertedID.(primitive.ObjectID), true},
+		{"soft-deleted", gone.InsertedID.(primitive.ObjectID), false},
+		{"missing", primitive.NewObjectID(), false},
+	} {
+		got, err := itemLive(collection, tt.id)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: itemLive = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCreateItem_OwnerQuota(t *testing.T) {
+	setupTestCollection(t)
+	prev := maxItemsPerOwner
+	maxItemsPerOwner = 2
+	defer func() { maxItemsPerOwner = prev }()
+
+	create := func(owner string) int {
+		req := httptest.NewRequest(http.MethodPost, "/todo", strings.NewReader(`{"description":"item"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(userIDHeader, owner)
+		rec := httptest.NewRecorder()
+		CreateItem(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := create("alice"); code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d", i, code)
+		}
+	}
+	if code := create("alice"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 at the quota, got %d", code)
+	}
+	if code := create("bob"); code != http.StatusCreated {
+		t.Fatalf("other owners are not affected, got %d", code)
+	}
+}
+
+func TestGetNextItem(t *testing.T) {
+	collection := setupTestCollection(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "done work", "completed": true, "tags": bson.A{"work"}, "createdAt": base},
+		bson.M{"description": "home chore", "completed": false, "tags": bson.A{"home"}, "createdAt": base.Add(time.Hour)},
+		bson.M{"description": "newer work", "completed": false, "tags": bson.A{"work"}, "createdAt": base.Add(3 * time.Hour)},
+		bson.M{"description": "older work", "completed": false, "tags": bson.A{"urgent", "work"}, "createdAt": base.Add(2 * time.Hour)},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetNextItem(rec, httptest.NewRequest(http.MethodGet, "/todo/next?tag=work", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var item TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if item.Description != "older work" {
+		t.Fatalf("expected the oldest incomplete work item, got %q", item.Description)
+	}
+
+	rec = httptest.NewRecorder()
+	GetNextItem(rec, httptest.NewRequest(http.MethodGet, "/todo/next?tag=garden", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unused tag, got %d", rec.Code)
+	}
+}
+
+func TestGetNextItem_MissingTag(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetNextItem(rec, httptest.NewRequest(http.MethodGet, "/todo/next?tag=%20", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestGetRecentlyCompleted(t *testing.T) {
+	collection := setupTestCollection(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rec := httptest.NewRecorder()
+	GetRecentlyCompleted(rec, httptest.NewRequest(http.MethodGet, "/todo/recently-completed", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("expected 200 with [], got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "open", "completed": false, "createdAt": base},
+		bson.M{"description": "first done", "completed": true, "createdAt": base, "completedAt": base.Add(time.Hour)},
+		bson.M{"description": "last done", "completed": true, "createdAt": base, "completedAt": base.Add(3 * time.Hour)},
+		bson.M{"description": "middle done", "completed": true, "createdAt": base, "completedAt": base.Add(2 * time.Hour)},
+		bson.M{"description": "deleted done", "completed": true, "createdAt": base, "completedAt": base.Add(4 * time.Hour), "deletedAt": base},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	GetRecentlyCompleted(rec, httptest.NewRequest(http.MethodGet, "/todo/recently-completed?n=2", nil))
+	var items []TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != 2 || items[0].Description != "last done" || items[1].Description != "middle done" {
+		t.Fatalf("expected last done, middle done; got %+v", items)
+	}
+}
+
+func TestGetRecentlyCompleted_InvalidN(t *testing.T) {
+	for _, query := range []string{"?n=0", "?n=-1", "?n=five"} {
+		rec := httptest.NewRecorder()
+		GetRecentlyCompleted(rec, httptest.NewRequest(http.MethodGet, "/todo/recently-completed"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestResetCollection_KeepsIndexes(t *testing.T) {
+	collection := setupTestCollection(t)
+	ensureIndexes(collection, false)
+
+	rec := httptest.NewRecorder()
+	ResetCollection(rec, httptest.NewRequest(http.MethodPost, "/todo/reset?confirm=reset", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", rec.Code, rec.Body.String())
+	}
+
+	cur, err := collection.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	var indexes []bson.M
+	if err := cur.All(context.TODO(), &indexes); err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	names := map[string]bool{}
+	for _, index := range indexes {
+		names[index["name"].(string)] = true
+	}
+	for _, model := range todoIndexes(false) {
+		if name := *model.Options.Name; !names[name] {
+			t.Errorf("index %s is missing after the reset; have %v", name, names)
+		}
+	}
+}
+
+func TestHeadListEndpoints(t *testing.T) {
+	collection := setupTestCollection(t)
+	t.Cleanup(func() { auditCollection(collection).Drop(context.TODO()) })
+	res, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "open", "completed": false, "tags": bson.A{"work"}},
+		bson.M{"description": "done", "completed": true},
+		bson.M{"description": "untagged", "completed": false, "tags": bson.A{}},
+		bson.M{"description": "gone", "completed": false, "deletedAt": time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := auditCollection(collection).InsertOne(context.TODO(), AuditEntry{Time: time.Now(), Operation: auditCreate, ItemID: res.InsertedIDs[0].(primitive.ObjectID)}); err != nil {
+		t.Fatalf("insert audit entry: %v", err)
+	}
+
+	tests := []struct {
+		handler http.HandlerFunc
+		target  string
+		want    string
+	}{
+		{HeadAllItems, "/todo", "3"},
+		{HeadAllItems, "/todo?completed=false&tag=work", "1"},
+		{HeadCompletedItems, "/todo-completed", "1"},
+		{HeadIncompleteItems, "/todo-incomplete", "2"},
+		{HeadUntaggedItems, "/todo/untagged", "2"},
+		{HeadAuditLog, "/todo/audit?operation=create", "1"},
+		{HeadAuditLog, "/todo/audit?operation=delete", "0"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodHead, tt.target, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != tt.want || rec.Body.Len() != 0 {
+			t.Errorf("HEAD %s: got %d with X-Total-Count %q and %d body bytes, want %s", tt.target, rec.Code, rec.Header().Get("X-Total-Count"), rec.Body.Len(), tt.want)
+		}
+	}
+}
+
+func TestHeadListEndpoints_InvalidParams(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		target  string
+	}{
+		{HeadAllItems, "/todo?completed=maybe"},
+		{HeadCompletedItems, "/todo-completed?priority=urgent"},
+		{HeadIncompleteItems, "/todo-incomplete?tag_mode=some"},
+		{HeadAuditLog, "/todo/audit?operation=read"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodHead, tt.target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("HEAD %s: expected 400, got %d", tt.target, rec.Code)
+		}
+	}
+}
+
+func TestGetDistinctTags(t *testing.T) {
+	collection := setupTestCollection(t)
+
+	rec := httptest.NewRecorder()
+	GetDistinctTags(rec, httptest.NewRequest(http.MethodGet, "/todo/tags/distinct", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("expected 200 with [], got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "a", "completed": false, "tags": bson.A{"work", "home"}, "owner": "alice"},
+		bson.M{"description": "b", "completed": false, "tags": bson.A{"errands", "work"}, "owner": "bob"},
+		bson.M{"description": "c", "completed": false},
+		bson.M{"description": "d", "completed": false, "tags": bson.A{"gone"}, "deletedAt": time.Now()},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	GetDistinctTags(rec, httptest.NewRequest(http.MethodGet, "/todo/tags/distinct", nil))
+	var tags []string
+	if err := json.NewDecoder(rec.Body).Decode(&tags); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if want := []string{"errands", "home", "work"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("got %v, want %v", tags, want)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/tags/distinct?mine=true", nil)
+	req.Header.Set(userIDHeader, "alice")
+	rec = httptest.NewRecorder()
+	GetDistinctTags(rec, req)
+	tags = nil
+	if err := json.NewDecoder(rec.Body).Decode(&tags); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if want := []string{"home", "work"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("got %v, want %v", tags, want)
+	}
+}
+
+func TestGetDistinctTags_Mine(t *testing.T) {
+	for query, status := range map[string]int{"?mine=maybe": http.StatusBadRequest, "?mine=true": http.StatusUnauthorized} {
+		rec := httptest.NewRecorder()
+		GetDistinctTags(rec, httptest.NewRequest(http.MethodGet, "/todo/tags/distinct"+query, nil))
+		if rec.Code != status {
+			t.Errorf("%q: expected %d, got %d", query, status, rec.Code)
+		}
+	}
+}
+
+func TestGetNearestItem_InvalidTime(t *testing.T) {
+	for _, query := range []string{"", "?time=yesterday", "?time=2024-01-01"} {
+		rec := httptest.NewRecorder()
+		GetNearestItem(rec, httptest.NewRequest(http.MethodGet, "/todo/nearest"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestGetNearestItem(t *testing.T) {
+	collection := setupTestCollection(t)
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, err := collection.InsertMany(context.TODO(), []interface{}{
+		bson.M{"description": "early", "completed": false, "createdAt": base.Add(-48 * time.Hour)},
+		bson.M{"description": "close", "completed": false, "createdAt": base.Add(3 * time.Hour)},
+		bson.M{"description": "late", "completed": false, "createdAt": base.Add(72 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	GetNearestItem(rec, httptest.NewRequest(http.MethodGet, "/todo/nearest?time="+base.Format(time.RFC3339), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var item TodoItemModel
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if item.Description != "close" {
+		t.Fatalf("expected the item 3h away, got %q", item.Description)
+	}
+}
+
+func TestReadyz_ReadinessDelay(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	prevDB, prevReadyAt, prevIndexes := tododb, readyAt, indexesReady.Load()
+	defer func() {
+		tododb, readyAt = prevDB, prevReadyAt
+		indexesReady.Store(prevIndexes)
+	}()
+	tododb = client.Database("todolist").Collection("TodoItemModel")
+	indexesReady.Store(true)
+
+	readyAt = time.Now().Add(time.Minute)
+	rec := httptest.NewRecorder()
+	Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "warming up") {
+		t.Fatalf("expected 503 while warming up, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	readyAt = time.Now().Add(-time.Second)
+	rec = httptest.NewRecorder()
+	Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the delay, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FieldError is one validation problem, tied to the field that caused it
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationResult is returned by POST /todo/validate
+type ValidationResult struct {
+	Valid  bool         `json:"valid"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// decodeFieldError reports a body decode error against the field named in
+// it, or "body"
+func decodeFieldError(err error) FieldError {
+	field := "body"
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field = typeErr.Field
+	}
+	return FieldError{Field: field, Message: err.Error()}
+}
+
+// ValidateItem runs the create validation on a proposed item without saving
+// it, so a UI can check a form before submitting. It accepts the same JSON
+// body or form fields as CreateItem and answers 200 {"valid": true}, or 400
+// with every field error found. A body that can't be decoded is reported
+// against the field named in the decode error, or "body".
+func ValidateItem(w http.ResponseWriter, r *http.Request) {
+	result := ValidationResult{Valid: true}
+	newItem, err := decodeNewItem(r)
+	if err != nil {
+		result.Errors = []FieldError{decodeFieldError(err)}
+	} else {
+		result.Errors = newItem.fieldErrors()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Errors) > 0 {
+		log.WithFields(log.Fields{"errors": len(result.Errors)}).Info("Proposed TodoItem is invalid")
+		result.Valid = false
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(result)
+}
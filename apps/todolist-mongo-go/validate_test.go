@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateItem(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+		fields []string
+	}{
+		{`{"description":"buy milk","priority":"high","tags":["home"]}`, http.StatusOK, nil},
+		{`{"description":" ","priority":"urgent","tags":[""]}`, http.StatusBadRequest, []string{"description", "priority", "tags"}},
+		{`{"description":"x","completed":"yes"}`, http.StatusBadRequest, []string{"completed"}},
+		{`{"description":"x","dueDate":"tomorrow"}`, http.StatusBadRequest, []string{"body"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/todo/validate", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		ValidateItem(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.body, tt.status, rec.Code, rec.Body.String())
+		}
+		var result ValidationResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("%s: decode: %v", tt.body, err)
+		}
+		if result.Valid != (tt.status == http.StatusOK) {
+			t.Fatalf("%s: valid = %v", tt.body, result.Valid)
+		}
+		if len(result.Errors) != len(tt.fields) {
+			t.Fatalf("%s: expected errors on %v, got %+v", tt.body, tt.fields, result.Errors)
+		}
+		for i, field := range tt.fields {
+			if result.Errors[i].Field != field {
+				t.Errorf("%s: error %d is on %q, want %q", tt.body, i, result.Errors[i].Field, field)
+			}
+		}
+	}
+}